@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// HTTPCredential 某个主机的HTTP认证账号，Basic和Digest由浏览器按服务器的质询方式处理
+type HTTPCredential struct {
+	Host     string `json:"host,omitempty"` // 主机名，"*.example.com"匹配子域名，"*"匹配所有主机，为空时为截图URL的主机
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ClientCertificate mTLS客户端证书，PEM和PKCS#12任选其一
+type ClientCertificate struct {
+	Hosts    []string `json:"hosts,omitempty"`    // 使用证书的主机，规则同HTTPCredential.Host，为空时为截图URL的主机
+	CertPEM  string   `json:"certPem,omitempty"`  // PEM格式的证书链，可以同时包含私钥
+	KeyPEM   string   `json:"keyPem,omitempty"`   // PEM格式的私钥
+	PKCS12   string   `json:"pkcs12,omitempty"`   // Base64编码的PKCS#12(.p12/.pfx)文件内容
+	Password string   `json:"password,omitempty"` // PKCS#12的密码
+}
+
+// tlsCertificate 解析证书和私钥
+func (c *ClientCertificate) tlsCertificate() (tls.Certificate, error) {
+	if c.PKCS12 != "" {
+		data, err := base64.StdEncoding.DecodeString(c.PKCS12)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("PKCS#12内容不是有效的Base64: %v", err)
+		}
+		key, cert, chain, err := pkcs12.DecodeChain(data, c.Password)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("解析PKCS#12客户端证书失败: %v", err)
+		}
+		tlsCert := tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+		for _, ca := range chain {
+			tlsCert.Certificate = append(tlsCert.Certificate, ca.Raw)
+		}
+		return tlsCert, nil
+	}
+	if c.CertPEM == "" {
+		return tls.Certificate{}, errors.New("客户端证书缺少certPem或pkcs12")
+	}
+	keyPEM := c.KeyPEM
+	if keyPEM == "" {
+		// 证书文件中同时包含私钥
+		keyPEM = c.CertPEM
+	}
+	cert, err := tls.X509KeyPair([]byte(c.CertPEM), []byte(keyPEM))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("解析PEM客户端证书失败: %v", err)
+	}
+	return cert, nil
+}
+
+// isPEM 判断文件内容是否为PEM格式
+func isPEM(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil
+}
+
+// validateAuth 检查HTTP认证账号和客户端证书
+func validateAuth(creds []HTTPCredential, cert *ClientCertificate) error {
+	for _, c := range creds {
+		if c.Username == "" {
+			return fmt.Errorf("主机 %q 的HTTP认证缺少用户名", c.Host)
+		}
+	}
+	if cert != nil {
+		if _, err := cert.tlsCertificate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchHost 判断主机名是否匹配规则："*.example.com"匹配其子域名，"*"匹配所有主机，空规则不匹配任何主机
+func matchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if pattern == "" {
+		return false
+	}
+	if pattern == host {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return false
+}
+
+// requestAuth 拦截请求所需的认证信息
+type requestAuth struct {
+	creds     []HTTPCredential
+	certHosts []string
+	client    *http.Client // 携带客户端证书的HTTP客户端，没有证书时为nil
+	proxy     *proxyServer // 需要认证的代理，回答代理的认证质询
+	// 严格模式下按CA证书文件校验服务器证书的HTTP客户端，未指定CA证书文件时为nil
+	caClient *http.Client
+	upstream *proxyServer // 标签页使用的代理，检查证书时同样使用
+
+	mu       sync.Mutex
+	answered map[fetch.RequestID]bool // 已提供过账号的请求，再次质询说明账号错误
+	caChecks map[string]*caCheck      // 按源(主机:端口)缓存的证书检查结果
+}
+
+// caCheck 某个源的服务器证书是否只有加上CA证书文件才能通过校验
+type caCheck struct {
+	once   sync.Once
+	needCA bool
+}
+
+// credential 返回匹配主机的账号
+func (a *requestAuth) credential(host string) *HTTPCredential {
+	for i := range a.creds {
+		if matchHost(a.creds[i].Host, host) {
+			return &a.creds[i]
+		}
+	}
+	return nil
+}
+
+// useCert 判断请求是否需要通过客户端证书发出
+func (a *requestAuth) useCert(host string) bool {
+	if a.client == nil {
+		return false
+	}
+	for _, h := range a.certHosts {
+		if matchHost(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// viaCABundle 判断请求的服务器证书是否需要按CA证书文件校验。浏览器只能识别服务器证书链中出现的额外CA，
+// 这类请求改由校验CA证书文件的Go客户端发出；其余请求仍由浏览器按严格模式校验
+func (a *requestAuth) viaCABundle(ctx context.Context, u *url.URL) bool {
+	if a.caClient == nil || u.Scheme != "https" {
+		return false
+	}
+	a.mu.Lock()
+	check, ok := a.caChecks[u.Host]
+	if !ok {
+		check = &caCheck{}
+		a.caChecks[u.Host] = check
+	}
+	a.mu.Unlock()
+	check.once.Do(func() {
+		chain, err := probeCertificate(ctx, u.Scheme+"://"+u.Host+"/", a.upstream)
+		if err != nil {
+			return
+		}
+		check.needCA = verifyChain(chain, u.Hostname(), nil) != nil && verifyChain(chain, u.Hostname(), rootCAs()) == nil
+	})
+	return check.needCA
+}
+
+// newRequestAuth 根据截图参数和标签页使用的代理准备认证信息，不需要拦截请求时返回nil
+func newRequestAuth(opts CaptureOptions, target string, proxy *proxyServer) (*requestAuth, error) {
+	_, _, proxyAuth := proxy.credentials()
+	checkCA := appCAs != nil && opts.tlsMode() == tlsStrict
+	if len(opts.HTTPAuth) == 0 && opts.ClientCert == nil && !proxyAuth && !checkCA {
+		return nil, nil
+	}
+	// 未指定主机的账号和证书只用于截图URL的主机，避免发给第三方的iframe或子资源
+	var targetHost string
+	if u, err := url.Parse(target); err == nil {
+		targetHost = u.Hostname()
+	}
+	a := &requestAuth{
+		upstream: proxy,
+		answered: make(map[fetch.RequestID]bool),
+		caChecks: make(map[string]*caCheck),
+	}
+	for _, c := range opts.HTTPAuth {
+		if c.Host == "" {
+			c.Host = targetHost
+		}
+		a.creds = append(a.creds, c)
+	}
+	if proxyAuth {
+		a.proxy = proxy
+	}
+	if c := opts.ClientCert; c != nil {
+		cert, err := c.tlsCertificate()
+		if err != nil {
+			return nil, err
+		}
+		a.certHosts = c.Hosts
+		if len(a.certHosts) == 0 {
+			a.certHosts = []string{targetHost}
+		}
+		// 与浏览器一致，只在严格模式下校验服务器证书，宽松模式下由截图结果记录证书问题
+		a.client = forwardClient(&tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			RootCAs:            rootCAs(),
+			InsecureSkipVerify: opts.tlsMode() != tlsStrict,
+		}, proxy)
+	}
+	if checkCA {
+		a.caClient = forwardClient(&tls.Config{RootCAs: rootCAs()}, proxy)
+	}
+	return a, nil
+}
+
+// forwardClient 创建代替浏览器发出请求的HTTP客户端，与浏览器使用同一个代理
+func forwardClient(config *tls.Config, proxy *proxyServer) *http.Client {
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: config,
+	}
+	if proxy != nil {
+		transport.Proxy = proxy.httpProxy()
+	}
+	return &http.Client{
+		Transport: transport,
+		// 重定向交给浏览器处理，跳转后的请求会再次被拦截
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// interceptRequests 启用Fetch拦截：回答HTTP认证质询，并把需要客户端证书或CA证书文件的请求改由Go发出。
+// 监听随ctx结束而停止
+func interceptRequests(a *requestAuth) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if a == nil {
+			return nil
+		}
+		execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch e := ev.(type) {
+			case *fetch.EventAuthRequired:
+				go a.answerAuth(execCtx, e)
+			case *fetch.EventRequestPaused:
+				go a.handleRequest(execCtx, e)
+			}
+		})
+		err := fetch.Enable().
+			WithPatterns([]*fetch.RequestPattern{{URLPattern: "*"}}).
+			WithHandleAuthRequests(len(a.creds) > 0 || a.proxy != nil).
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("启用请求拦截失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// answerAuth 为匹配的主机或代理提供账号；没有匹配的账号或账号已被拒绝时取消认证，页面显示服务器返回的401或407
+func (a *requestAuth) answerAuth(ctx context.Context, e *fetch.EventAuthRequired) {
+	resp := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	a.mu.Lock()
+	retry := a.answered[e.RequestID]
+	a.answered[e.RequestID] = true
+	a.mu.Unlock()
+	if e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		if a.proxy != nil && !retry {
+			username, password, _ := a.proxy.credentials()
+			resp = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: username,
+				Password: password,
+			}
+		}
+	} else if u, err := url.Parse(e.AuthChallenge.Origin); err == nil {
+		if c := a.credential(u.Hostname()); c != nil && !retry {
+			resp = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: c.Username,
+				Password: c.Password,
+			}
+		}
+	}
+	fetch.ContinueWithAuth(e.RequestID, resp).Do(ctx)
+}
+
+// handleRequest 放行普通请求，需要客户端证书或CA证书文件的请求由Go发出后把响应交给浏览器
+func (a *requestAuth) handleRequest(ctx context.Context, e *fetch.EventRequestPaused) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		fetch.ContinueRequest(e.RequestID).Do(ctx)
+		return
+	}
+	switch {
+	case a.useCert(u.Hostname()):
+		if err := a.fulfill(ctx, e, a.client); err != nil {
+			fmt.Printf("客户端证书请求 %s 失败: %v\n", e.Request.URL, err)
+			fetch.FailRequest(e.RequestID, network.ErrorReasonConnectionFailed).Do(ctx)
+		}
+	case a.viaCABundle(ctx, u):
+		if err := a.fulfill(ctx, e, a.caClient); err != nil {
+			fmt.Printf("按CA证书文件发出请求 %s 失败: %v\n", e.Request.URL, err)
+			fetch.FailRequest(e.RequestID, network.ErrorReasonConnectionFailed).Do(ctx)
+		}
+	default:
+		fetch.ContinueRequest(e.RequestID).Do(ctx)
+	}
+}
+
+// 转发请求时不复制的请求头，由Go的HTTP客户端自行设置
+var skipForwardHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"accept-encoding":   true, // 让Go自动解压响应，浏览器收到的是未压缩的内容
+	"transfer-encoding": true,
+}
+
+// fulfill 用指定的HTTP客户端代替浏览器发出请求
+func (a *requestAuth) fulfill(ctx context.Context, e *fetch.EventRequestPaused, client *http.Client) error {
+	var body bytes.Buffer
+	for _, entry := range e.Request.PostDataEntries {
+		data, err := base64.StdEncoding.DecodeString(entry.Bytes)
+		if err != nil {
+			return fmt.Errorf("解码请求体失败: %v", err)
+		}
+		body.Write(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Request.Method, e.Request.URL, &body)
+	if err != nil {
+		return err
+	}
+	for name, value := range e.Request.Headers {
+		if strings.HasPrefix(name, ":") || skipForwardHeaders[strings.ToLower(name)] {
+			continue
+		}
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			req.Header.Add(name, v)
+		}
+	}
+	// 拦截到的请求头中不含Cookie，从浏览器中读取
+	cookies, err := network.GetCookies().WithURLs([]string{e.Request.URL}).Do(ctx)
+	if err == nil && len(cookies) > 0 {
+		pairs := make([]string, len(cookies))
+		for i, c := range cookies {
+			pairs[i] = c.Name + "=" + c.Value
+		}
+		req.Header.Set("Cookie", strings.Join(pairs, "; "))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var headers []*fetch.HeaderEntry
+	for name, values := range resp.Header {
+		for _, v := range values {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: v})
+		}
+	}
+	// 响应中的Cookie写回浏览器，后续请求和页面脚本可以读取
+	if set := resp.Cookies(); len(set) > 0 {
+		var params []*network.CookieParam
+		for _, c := range set {
+			params = append(params, &network.CookieParam{
+				Name: c.Name, Value: c.Value, URL: e.Request.URL,
+				Domain: c.Domain, Path: c.Path, Secure: c.Secure, HTTPOnly: c.HttpOnly,
+			})
+		}
+		network.SetCookies(params).Do(ctx)
+	}
+	return fetch.FulfillRequest(e.RequestID, int64(resp.StatusCode)).
+		WithResponseHeaders(headers).
+		WithBody(base64.StdEncoding.EncodeToString(data)).
+		Do(ctx)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"Example.COM", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"*", "anything.test", true},
+		{"", "example.com", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchHost(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
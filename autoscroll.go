@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// 自动滚动的默认参数
+const (
+	defaultAutoScrollDelay   = 200   // 毫秒
+	defaultAutoScrollMaxTime = 15000 // 毫秒
+)
+
+// AutoScrollOptions 截图前自动滚动到页面底部，触发懒加载的图片和内容
+type AutoScrollOptions struct {
+	Step    int `json:"step,omitempty"`    // 每次滚动的距离(CSS像素)，默认为视口高度
+	Delay   int `json:"delay,omitempty"`   // 每次滚动后的等待时间(毫秒)，默认200
+	MaxTime int `json:"maxTime,omitempty"` // 滚动和等待图片加载的总时长上限(毫秒)，默认15000
+}
+
+// validate 检查滚动参数
+func (a *AutoScrollOptions) validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.Step < 0 || a.Delay < 0 || a.MaxTime < 0 {
+		return fmt.Errorf("自动滚动的参数不能为负数")
+	}
+	return nil
+}
+
+// autoScrollScript 逐步滚动到底部，页面高度在到达底部后不再增长时停止；
+// 随后等待已出现的图片加载完成，最后回到顶部
+const autoScrollScript = `(function(step, delay, maxTime) {
+	var deadline = Date.now() + maxTime;
+	var sleep = function(ms) {
+		return new Promise(function(resolve) { setTimeout(resolve, ms); });
+	};
+	var height = function() {
+		return Math.max(document.documentElement.scrollHeight, document.body ? document.body.scrollHeight : 0);
+	};
+	step = step || window.innerHeight;
+	return (async function() {
+		var scrolls = 0;
+		while (Date.now() < deadline) {
+			window.scrollBy(0, step);
+			scrolls++;
+			await sleep(delay);
+			if (window.scrollY + window.innerHeight >= height() - 1) {
+				// 到达底部后再等一轮，看是否加载出新内容
+				var before = height();
+				await sleep(delay);
+				if (height() <= before) {
+					break;
+				}
+			}
+		}
+		var pending = Array.from(document.images).filter(function(img) { return !img.complete; });
+		await Promise.race([
+			Promise.all(pending.map(function(img) {
+				return new Promise(function(resolve) {
+					img.addEventListener('load', resolve, {once: true});
+					img.addEventListener('error', resolve, {once: true});
+				});
+			})),
+			sleep(Math.max(deadline - Date.now(), 0))
+		]);
+		window.scrollTo(0, 0);
+		await new Promise(function(resolve) {
+			requestAnimationFrame(function() { requestAnimationFrame(resolve); });
+		});
+		return scrolls;
+	})();
+})(%d, %d, %d)`
+
+// autoScroll 执行自动滚动，返回滚动的次数
+func autoScroll(ctx context.Context, a *AutoScrollOptions) (int, error) {
+	delay, maxTime := a.Delay, a.MaxTime
+	if delay <= 0 {
+		delay = defaultAutoScrollDelay
+	}
+	if maxTime <= 0 {
+		maxTime = defaultAutoScrollMaxTime
+	}
+	var scrolls int
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(autoScrollScript, a.Step, delay, maxTime), &scrolls,
+		func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) },
+	))
+	if err != nil {
+		return 0, fmt.Errorf("自动滚动失败: %v", err)
+	}
+	return scrolls, nil
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// 未指定等待策略时的固定延迟，与早期版本的行为一致
+const defaultWaitDelay = 2000
+
+// JPEG和WebP的默认压缩质量
+const defaultImageQuality = 90
+
+// imageFormat 截图格式及其MIME类型
+type imageFormat struct {
+	format page.CaptureScreenshotFormat
+	mime   string
+}
+
+// imageFormats 支持的输出格式，jpg是jpeg的别名，pdf由printPDF生成
+var imageFormats = map[string]imageFormat{
+	"png":  {page.CaptureScreenshotFormatPng, "image/png"},
+	"jpeg": {page.CaptureScreenshotFormatJpeg, "image/jpeg"},
+	"jpg":  {page.CaptureScreenshotFormatJpeg, "image/jpeg"},
+	"webp": {page.CaptureScreenshotFormatWebp, "image/webp"},
+	"pdf":  {"", mimePDF},
+}
+
+// WaitOptions 截图前的等待策略，可以组合使用，按网络空闲、元素、JS条件、固定延迟的顺序依次等待
+type WaitOptions struct {
+	NetworkIdle int    `json:"networkIdle,omitempty"` // 连续多少毫秒没有网络请求视为空闲，0表示不等待
+	MaxInflight int    `json:"maxInflight,omitempty"` // 判断网络空闲时允许保持的请求数，用于忽略长连接
+	Selector    string `json:"selector,omitempty"`    // 等待匹配该CSS选择器的元素可见
+	Expression  string `json:"expression,omitempty"`  // 等待该JavaScript表达式的结果为真
+	Delay       int    `json:"delay,omitempty"`       // 最后再固定等待的毫秒数
+}
+
+// isZero 判断是否没有设置任何等待条件
+func (w *WaitOptions) isZero() bool {
+	return w == nil || (w.NetworkIdle <= 0 && w.Selector == "" && w.Expression == "" && w.Delay <= 0)
+}
+
+// CaptureOptions 单次截图的参数，对应/capture和批量任务请求中的字段
+type CaptureOptions struct {
+	FullPage   bool               `json:"fullPage"`
+	Timeout    int                `json:"timeout,omitempty"` // 超时时间(秒)，0表示使用调用方的默认值
+	Wait       *WaitOptions       `json:"wait,omitempty"`
+	Viewport   *ViewportOptions   `json:"viewport,omitempty"`   // 为空时使用1280×800的桌面视口
+	Format     string             `json:"format,omitempty"`     // png、jpeg、webp或pdf，默认png
+	Quality    int                `json:"quality,omitempty"`    // JPEG和WebP的压缩质量(1-100)，默认90
+	PDF        *PDFOptions        `json:"pdf,omitempty"`        // format为pdf时的打印参数
+	Selector   string             `json:"selector,omitempty"`   // 只截取匹配该CSS选择器的元素
+	Padding    int                `json:"padding,omitempty"`    // 元素截图四周额外保留的像素
+	AllMatches bool               `json:"allMatches,omitempty"` // 截取所有可见的匹配元素，每个元素一张图片，否则只截取第一个可见的
+	Clip       *ClipRect          `json:"clip,omitempty"`       // 只截取该区域，优先于fullPage
+	Scroll     *ScrollOptions     `json:"scroll,omitempty"`     // 截图前先滚动到锚点或坐标
+	Tiled      *TileOptions       `json:"tiled,omitempty"`      // 分块截取整页并在本地拼接，用于超长页面
+	AutoScroll *AutoScrollOptions `json:"autoScroll,omitempty"` // 截图前滚动到底部触发懒加载，再回到顶部
+	Steps      []Step             `json:"steps,omitempty"`      // 页面就绪后、截图前依次执行的交互步骤
+
+	// 请求头，未设置时使用配置文件中的值；Headers与配置文件中的请求头合并
+	Headers        map[string]string `json:"headers,omitempty"`
+	UserAgent      string            `json:"userAgent,omitempty"`
+	AcceptLanguage string            `json:"acceptLanguage,omitempty"`
+
+	// 导航前写入的Cookie，依次合并命名会话、cookies.txt内容和Cookies列表
+	Cookies    []Cookie `json:"cookies,omitempty"`
+	CookiesTxt string   `json:"cookiesTxt,omitempty"` // Netscape cookies.txt或JSON列表的文本内容
+	Session    string   `json:"session,omitempty"`    // 已保存的会话名称
+
+	Profile string `json:"profile,omitempty"` // 在该浏览器配置的持久化用户数据目录中截图，需要时先执行登录宏
+
+	// 目标需要认证时使用：HTTPAuth回答Basic/Digest质询，ClientCert用于要求客户端证书(mTLS)的主机
+	HTTPAuth   []HTTPCredential   `json:"httpAuth,omitempty"`
+	ClientCert *ClientCertificate `json:"clientCert,omitempty"`
+
+	Proxy *ProxyOptions `json:"proxy,omitempty"` // 为空时使用配置文件中的默认代理
+
+	TLS string `json:"tls,omitempty"` // 证书检查模式，strict或lenient，为空时使用配置文件中的值
+}
+
+// timeout 返回超时时间，未设置时使用defaultSec
+func (o CaptureOptions) timeout(defaultSec int) time.Duration {
+	if o.Timeout > 0 {
+		return time.Duration(o.Timeout) * time.Second
+	}
+	return time.Duration(defaultSec) * time.Second
+}
+
+// validateCaptureURL 检查截图地址，只允许http和https，
+// 避免服务监听在外部地址时被用来截取本机的file://或chrome://页面
+func validateCaptureURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL必须以http://或https://开头: %s", raw)
+	}
+	return nil
+}
+
+// validate 在开始截图前检查参数，便于尽早返回错误
+func (o CaptureOptions) validate() error {
+	if _, ok := imageFormats[strings.ToLower(o.Format)]; !ok && o.Format != "" {
+		return fmt.Errorf("不支持的输出格式 %q，可用: png, jpeg, webp, pdf", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("图片质量 %d 超出范围(1-100)", o.Quality)
+	}
+	if o.Selector != "" && o.imageFormat().mime == mimePDF {
+		return errors.New("PDF格式不支持按元素截图")
+	}
+	if o.Clip != nil && (o.Selector != "" || o.imageFormat().mime == mimePDF) {
+		return errors.New("截图区域不能与元素截图或PDF格式同时使用")
+	}
+	if o.Tiled != nil && (o.Selector != "" || o.Clip != nil || o.imageFormat().mime == mimePDF) {
+		return errors.New("分块截图不能与元素截图、截图区域或PDF格式同时使用")
+	}
+	if err := validateHeaders(o.Headers); err != nil {
+		return err
+	}
+	if strings.ContainsAny(o.UserAgent+o.AcceptLanguage, "\r\n") {
+		return errors.New("UA和Accept-Language不能包含换行")
+	}
+	if _, err := o.cookies(); err != nil {
+		return err
+	}
+	if _, ok := appConfig.Profiles[o.Profile]; o.Profile != "" && !ok {
+		return fmt.Errorf("浏览器配置 %q 不存在", o.Profile)
+	}
+	if err := o.Proxy.validate(); err != nil {
+		return err
+	}
+	if o.Proxy != nil && o.Profile != "" {
+		return errors.New("浏览器配置使用启动时的代理，不能在请求中单独设置代理")
+	}
+	if err := validateTLSMode(o.TLS); err != nil {
+		return err
+	}
+	if err := validateAuth(o.HTTPAuth, o.ClientCert); err != nil {
+		return err
+	}
+	if err := validateSteps(o.Steps); err != nil {
+		return err
+	}
+	if err := o.AutoScroll.validate(); err != nil {
+		return err
+	}
+	if err := o.Tiled.validate(); err != nil {
+		return err
+	}
+	if err := o.Clip.validate(); err != nil {
+		return err
+	}
+	if o.Padding < 0 {
+		return fmt.Errorf("元素边距 %d 不能为负数", o.Padding)
+	}
+	if err := o.PDF.validate(); err != nil {
+		return err
+	}
+	_, err := o.Viewport.resolve()
+	return err
+}
+
+// imageFormat 返回截图格式，未设置或无法识别时使用PNG
+func (o CaptureOptions) imageFormat() imageFormat {
+	if f, ok := imageFormats[strings.ToLower(o.Format)]; ok {
+		return f
+	}
+	return imageFormats["png"]
+}
+
+// screenshot 按格式和质量参数截图，clip不为空时只截取该区域，整页时截取超出视口的部分；
+// 格式为pdf时打印为PDF
+func (o CaptureOptions) screenshot(res *[]byte, clip *page.Viewport) chromedp.Action {
+	f := o.imageFormat()
+	if f.mime == mimePDF {
+		return printPDF(res, o.PDF)
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		params := o.screenshotParams()
+		switch {
+		case clip != nil:
+			params = params.WithClip(clip).WithCaptureBeyondViewport(true)
+		case o.FullPage:
+			params = params.WithCaptureBeyondViewport(true)
+		}
+		var err error
+		*res, err = params.Do(ctx)
+		return err
+	})
+}
+
+// screenshotParams 按格式和质量参数生成截图命令
+func (o CaptureOptions) screenshotParams() *page.CaptureScreenshotParams {
+	f := o.imageFormat()
+	params := page.CaptureScreenshot().WithFormat(f.format).WithFromSurface(true)
+	if f.format != page.CaptureScreenshotFormatPng {
+		quality := o.Quality
+		if quality <= 0 {
+			quality = defaultImageQuality
+		}
+		params = params.WithQuality(int64(quality))
+	}
+	return params
+}
+
+// CaptureResult 一次截图的结果
+type CaptureResult struct {
+	Image      []byte
+	Images     [][]byte // 按元素或分段截图时的全部图片，第一张与Image相同
+	MimeType   string   // 图片的MIME类型，与请求的格式一致
+	FinalURL   string   // 跟随跳转后的最终地址
+	Title      string   // 页面标题
+	StatusCode int      // 主文档的HTTP状态码
+	Proxy      string   // 使用的代理，直连时为空
+	// 主文档的服务器证书，非HTTPS页面为nil
+	Certificate *CertificateInfo
+	// 严格模式下因证书错误加载失败的其他请求，格式为"源: 错误"
+	CertificateErrors []string
+}
+
+// captureScreenshot 捕获指定URL的截图，ctx取消时会中止正在进行的截图
+func captureScreenshot(ctx context.Context, url string, opts CaptureOptions) (*CaptureResult, error) {
+	// 浏览器进程在截图过程中崩溃时，换一个标签页重试一次
+	for attempt := 0; ; attempt++ {
+		lease, err := acquireLease(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		res, err := captureWithLease(ctx, lease, url, opts)
+		lost := lease.BrowserLost()
+		lease.Release()
+		if err != nil && lost && attempt == 0 && ctx.Err() == nil {
+			fmt.Printf("URL %s 截图时浏览器进程退出，正在重试\n", url)
+			continue
+		}
+		return res, err
+	}
+}
+
+// captureWithLease 在租用的标签页中完成一次截图
+func captureWithLease(parent context.Context, lease *PageLease, url string, opts CaptureOptions) (*CaptureResult, error) {
+	vp, err := opts.Viewport.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return capturePage(parent, lease, url, opts, vp)
+}
+
+// capturePage 按指定视口加载页面并截图，同一标签页可以依次用不同视口多次调用
+func capturePage(parent context.Context, lease *PageLease, url string, opts CaptureOptions, vp DevicePreset) (*CaptureResult, error) {
+	// 设置超时，并在parent取消时中止chromedp任务
+	ctx, cancel := context.WithTimeout(lease.Context(), opts.timeout(60))
+	defer cancel()
+	stop := context.AfterFunc(parent, cancel)
+	defer stop()
+
+	if err := chromedp.Run(ctx, emulateViewport(vp)); err != nil {
+		return nil, fmt.Errorf("设置视口失败: %v", err)
+	}
+	if err := chromedp.Run(ctx, applyHeaders(opts.requestHeaders(), vp)); err != nil {
+		return nil, err
+	}
+	cookies, err := opts.cookies()
+	if err != nil {
+		return nil, err
+	}
+	if err := chromedp.Run(ctx, setCookies(cookies, url)); err != nil {
+		return nil, err
+	}
+	auth, err := newRequestAuth(opts, url, lease.Proxy())
+	if err != nil {
+		return nil, err
+	}
+	if err := chromedp.Run(ctx, interceptRequests(auth)); err != nil {
+		return nil, err
+	}
+	tlsMode := opts.tlsMode()
+	if err := chromedp.Run(ctx, applyTLSMode(tlsMode)); err != nil {
+		return nil, err
+	}
+
+	wait := opts.Wait
+	if wait.isZero() {
+		wait = &WaitOptions{Delay: defaultWaitDelay}
+	}
+
+	// 网络空闲需要从导航开始统计请求
+	var tracker *networkTracker
+	if wait.NetworkIdle > 0 {
+		tracker = trackNetwork(ctx)
+	}
+
+	// 严格模式下记录子资源、iframe等请求的证书错误
+	var failures *certFailures
+	if tlsMode == tlsStrict {
+		failures = trackCertFailures(ctx)
+	}
+
+	// 导航到URL并记录主文档的响应；需要CA证书文件才能校验的源已在请求拦截中改由Go发出
+	resp, err := chromedp.RunResponse(ctx, chromedp.Navigate(url))
+	if isCertificateError(err) {
+		// 严格模式下浏览器拒绝了证书，单独获取证书以便说明原因
+		return nil, &CertificateError{Certificate: inspectCertificate(ctx, url, lease.Proxy()), Err: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("执行截图任务失败: %v", err)
+	}
+
+	// 记录主文档的证书，严格模式下浏览器接受但校验发现问题的证书同样视为失败
+	docURL := url
+	if resp != nil {
+		docURL = resp.URL
+	}
+	cert := inspectCertificate(ctx, docURL, lease.Proxy())
+	if cert != nil && len(cert.Errors) > 0 && tlsMode == tlsStrict {
+		return nil, &CertificateError{Certificate: cert, Err: errors.New(strings.Join(cert.Errors, "; "))}
+	}
+
+	// 等待页面加载完成
+	if err := chromedp.Run(ctx, chromedp.WaitVisible(`body`, chromedp.ByQuery)); err != nil {
+		return nil, fmt.Errorf("等待页面加载失败: %v", err)
+	}
+	if err := waitForPage(ctx, wait, tracker); err != nil {
+		return nil, err
+	}
+
+	// 自动滚动一遍触发懒加载的内容
+	if opts.AutoScroll != nil {
+		if _, err := autoScroll(ctx, opts.AutoScroll); err != nil {
+			return nil, err
+		}
+	}
+
+	// 执行交互步骤，如打开菜单、关闭弹窗、填写搜索框
+	if err := runSteps(ctx, opts.Steps); err != nil {
+		return nil, err
+	}
+
+	// 按参数滚动页面
+	var scrollX, scrollY float64
+	if opts.Scroll != nil {
+		if scrollX, scrollY, err = scrollPage(ctx, opts.Scroll); err != nil {
+			return nil, err
+		}
+	}
+
+	// 存储截图结果
+	var buf []byte
+	var finalURL, title string
+
+	// 记录页面信息
+	err = chromedp.Run(ctx,
+		// 记录跳转后的地址和页面标题
+		chromedp.Location(&finalURL),
+		chromedp.Title(&title),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("执行截图任务失败: %v", err)
+	}
+
+	res := &CaptureResult{MimeType: opts.imageFormat().mime, FinalURL: finalURL, Title: title, Certificate: cert}
+	if proxy := lease.Proxy(); proxy != nil {
+		res.Proxy = proxy.String()
+	}
+	if failures != nil {
+		res.CertificateErrors = failures.list()
+	}
+	if opts.Selector != "" {
+		// 按元素截图，选择器没有匹配时直接返回其错误
+		if res.Images, err = captureElements(ctx, opts); err != nil {
+			return nil, err
+		}
+		res.Image = res.Images[0]
+	} else if opts.Tiled != nil {
+		// 分块截图并拼接，WebP会改为PNG输出
+		if res.Images, res.MimeType, err = captureTiled(ctx, opts); err != nil {
+			return nil, err
+		}
+		res.Image = res.Images[0]
+	} else {
+		// 按参数截取指定区域、整页或可见区域
+		var clip *page.Viewport
+		if opts.Clip != nil {
+			clip = opts.Clip.viewport(scrollX, scrollY)
+		}
+		if err := chromedp.Run(ctx, opts.screenshot(&buf, clip)); err != nil {
+			return nil, fmt.Errorf("执行截图任务失败: %v", err)
+		}
+		res.Image = buf
+	}
+	if resp != nil {
+		res.StatusCode = int(resp.Status)
+	}
+	return res, nil
+}
+
+// waitForPage 按等待策略依次等待各个条件满足
+func waitForPage(ctx context.Context, wait *WaitOptions, tracker *networkTracker) error {
+	if tracker != nil {
+		idle := time.Duration(wait.NetworkIdle) * time.Millisecond
+		if err := tracker.waitIdle(ctx, idle, wait.MaxInflight); err != nil {
+			return fmt.Errorf("等待网络空闲(%dms)失败: %v", wait.NetworkIdle, err)
+		}
+	}
+	if wait.Selector != "" {
+		if err := chromedp.Run(ctx, chromedp.WaitVisible(wait.Selector, chromedp.ByQuery)); err != nil {
+			return fmt.Errorf("等待元素 %s 失败: %v", wait.Selector, err)
+		}
+	}
+	if wait.Expression != "" {
+		poll := chromedp.Poll(wait.Expression, nil,
+			chromedp.WithPollingInterval(100*time.Millisecond),
+			chromedp.WithPollingTimeout(0), // 由ctx的超时控制
+		)
+		if err := chromedp.Run(ctx, poll); err != nil {
+			return fmt.Errorf("等待JS条件 %s 失败: %v", wait.Expression, err)
+		}
+	}
+	if wait.Delay > 0 {
+		if err := chromedp.Run(ctx, chromedp.Sleep(time.Duration(wait.Delay)*time.Millisecond)); err != nil {
+			return fmt.Errorf("固定等待失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// networkTracker 统计标签页中尚未完成的网络请求
+type networkTracker struct {
+	mu         sync.Mutex
+	inflight   map[network.RequestID]bool
+	lastChange time.Time
+}
+
+// trackNetwork 开始监听标签页的网络请求，需在导航前调用
+func trackNetwork(ctx context.Context) *networkTracker {
+	t := &networkTracker{
+		inflight:   make(map[network.RequestID]bool),
+		lastChange: time.Now(),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			t.inflight[e.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(t.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(t.inflight, e.RequestID)
+		default:
+			return
+		}
+		t.lastChange = time.Now()
+	})
+	return t
+}
+
+// waitIdle 等待进行中的请求数不超过maxInflight且持续idle时长
+func (t *networkTracker) waitIdle(ctx context.Context, idle time.Duration, maxInflight int) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		quiet := len(t.inflight) <= maxInflight && time.Since(t.lastChange) >= idle
+		t.mu.Unlock()
+		if quiet {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthFlagSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    HTTPCredential
+		wantErr bool
+	}{
+		{in: "admin:secret", want: HTTPCredential{Username: "admin", Password: "secret"}},
+		{in: "intranet.example.com=admin:secret", want: HTTPCredential{Username: "admin", Password: "secret", Host: "intranet.example.com"}},
+		{in: "*.example.com=admin:secret", want: HTTPCredential{Username: "admin", Password: "secret", Host: "*.example.com"}},
+		{in: "*=admin:secret", want: HTTPCredential{Username: "admin", Password: "secret", Host: "*"}},
+		{in: "intranet=admin:secret", want: HTTPCredential{Username: "admin", Password: "secret", Host: "intranet"}},
+		{in: "u:p@ss", want: HTTPCredential{Username: "u", Password: "p@ss"}},
+		{in: "u:P@ss.word", want: HTTPCredential{Username: "u", Password: "P@ss.word"}},
+		{in: "example.com=u:P@ss.word", want: HTTPCredential{Username: "u", Password: "P@ss.word", Host: "example.com"}},
+		{in: "u:a=b", want: HTTPCredential{Username: "u", Password: "a=b"}},
+		{in: "u:a:b", want: HTTPCredential{Username: "u", Password: "a:b"}},
+		{in: "u:", want: HTTPCredential{Username: "u"}},
+		{in: "admin", wantErr: true},
+		{in: ":secret", wantErr: true},
+		{in: "=admin:secret", wantErr: true},
+		{in: "example.com=admin", wantErr: true},
+	}
+	for _, tt := range tests {
+		var f authFlag
+		err := f.Set(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("authFlag.Set(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if want := (authFlag{tt.want}); !reflect.DeepEqual(f, want) {
+			t.Errorf("authFlag.Set(%q) = %+v, want %+v", tt.in, f, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ClipRect 截图区域(CSS像素)
+type ClipRect struct {
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	Relative bool    `json:"relative,omitempty"` // 坐标相对于滚动后的视口左上角，否则相对于文档左上角
+}
+
+// ScrollOptions 截图前的滚动位置，指定Anchor时滚动到该元素，否则滚动到X、Y
+type ScrollOptions struct {
+	Anchor string  `json:"anchor,omitempty"` // CSS选择器，如#section-2
+	X      float64 `json:"x,omitempty"`
+	Y      float64 `json:"y,omitempty"`
+}
+
+// validate 检查区域是否有效
+func (c *ClipRect) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Width <= 0 || c.Height <= 0 {
+		return fmt.Errorf("截图区域的宽高必须大于0")
+	}
+	if c.X < 0 || c.Y < 0 {
+		return fmt.Errorf("截图区域的坐标不能为负数")
+	}
+	if c.Width > maxViewportSize*2 || c.Height > maxViewportSize*2 {
+		return fmt.Errorf("截图区域 %g×%g 过大", c.Width, c.Height)
+	}
+	return nil
+}
+
+// viewport 转换为截图命令使用的文档坐标，scrollX、scrollY为当前滚动位置
+func (c *ClipRect) viewport(scrollX, scrollY float64) *page.Viewport {
+	x, y := c.X, c.Y
+	if c.Relative {
+		x += scrollX
+		y += scrollY
+	}
+	return &page.Viewport{X: x, Y: y, Width: c.Width, Height: c.Height, Scale: 1}
+}
+
+// parseClip 解析命令行中的区域"x,y,宽,高"
+func parseClip(s string) (*ClipRect, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("无效的截图区域 %q，格式为x,y,宽,高", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的截图区域 %q，格式为x,y,宽,高", s)
+		}
+		v[i] = f
+	}
+	return &ClipRect{X: v[0], Y: v[1], Width: v[2], Height: v[3]}, nil
+}
+
+// parseScroll 解析命令行中的滚动位置，"x,y"为坐标，其他视为锚点选择器
+func parseScroll(s string) *ScrollOptions {
+	if x, y, ok := strings.Cut(s, ","); ok {
+		fx, errX := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		fy, errY := strconv.ParseFloat(strings.TrimSpace(y), 64)
+		if errX == nil && errY == nil {
+			return &ScrollOptions{X: fx, Y: fy}
+		}
+	}
+	return &ScrollOptions{Anchor: s}
+}
+
+// scrollScript 滚动页面并等待两帧渲染完成，返回滚动后的位置；锚点不存在时返回null
+const scrollScript = `(function(anchor, x, y) {
+	if (anchor) {
+		var el = document.querySelector(anchor);
+		if (!el) {
+			return null;
+		}
+		el.scrollIntoView({block: 'start', inline: 'nearest'});
+	} else {
+		window.scrollTo(x, y);
+	}
+	return new Promise(function(resolve) {
+		requestAnimationFrame(function() {
+			requestAnimationFrame(function() {
+				resolve([window.scrollX, window.scrollY]);
+			});
+		});
+	});
+})(%s, %g, %g)`
+
+// scrollPage 按滚动参数滚动页面，返回滚动后的位置
+func scrollPage(ctx context.Context, s *ScrollOptions) (float64, float64, error) {
+	anchor, _ := json.Marshal(s.Anchor)
+	var pos []float64
+	err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(scrollScript, anchor, s.X, s.Y), &pos,
+		func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) },
+	))
+	if err != nil {
+		return 0, 0, fmt.Errorf("滚动页面失败: %v", err)
+	}
+	if len(pos) != 2 {
+		return 0, 0, errors.New("滚动页面失败: 锚点 " + s.Anchor + " 没有匹配的元素")
+	}
+	return pos[0], pos[1], nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// 默认配置文件路径，相对于当前工作目录
+const defaultConfigPath = "webcut.json"
+
+// Config 配置文件内容，未出现在文件中的字段保持默认值
+type Config struct {
+	// 截图保存目录
+	OutputDir string `json:"outputDir"`
+	// 命名会话(Cookie)的保存目录
+	SessionDir string `json:"sessionDir"`
+	// 浏览器配置(持久化的用户数据目录和登录状态)的保存目录
+	ProfileDir string `json:"profileDir"`
+	// 命名的浏览器配置，截图时通过profile参数使用
+	Profiles map[string]ProfileConfig `json:"profiles,omitempty"`
+	// 文件名模板，可用占位符见storage.go中的fileNameFields
+	FileNameTemplate string `json:"fileNameTemplate"`
+	// 是否自动将每次截图写入保存目录
+	AutoSave bool `json:"autoSave"`
+	// 每次截图默认附加的请求头，可被请求中的同名请求头覆盖
+	Headers map[string]string `json:"headers,omitempty"`
+	// 默认的User-Agent，为空时使用设备预设或浏览器的默认值
+	UserAgent string `json:"userAgent,omitempty"`
+	// 默认的Accept-Language，如zh-CN,zh;q=0.9
+	AcceptLanguage string `json:"acceptLanguage,omitempty"`
+	// 默认代理，请求中设置了proxy时以请求为准
+	Proxy *ProxyOptions `json:"proxy,omitempty"`
+	// 证书检查模式：lenient(默认)证书有错误时仍截图并记录错误，strict证书有错误时截图失败
+	TLS string `json:"tls,omitempty"`
+	// 额外信任的CA证书文件(PEM，可包含多个证书)，用于校验企业内部PKI签发的证书
+	CABundle string `json:"caBundle,omitempty"`
+}
+
+// defaultConfig 返回默认配置
+func defaultConfig() Config {
+	return Config{
+		OutputDir:        "screenshots",
+		SessionDir:       "sessions",
+		ProfileDir:       "profiles",
+		FileNameTemplate: defaultFileNameTemplate,
+		AutoSave:         true,
+	}
+}
+
+var appConfig = defaultConfig()
+
+// loadConfig 从JSON文件加载配置，文件不存在时使用默认配置
+func loadConfig(path string) error {
+	cfg := defaultConfig()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && path == defaultConfigPath {
+		appConfig, appCAs = cfg, nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = defaultConfig().OutputDir
+	}
+	if cfg.SessionDir == "" {
+		cfg.SessionDir = defaultConfig().SessionDir
+	}
+	if cfg.ProfileDir == "" {
+		cfg.ProfileDir = defaultConfig().ProfileDir
+	}
+	if cfg.FileNameTemplate == "" {
+		cfg.FileNameTemplate = defaultFileNameTemplate
+	}
+	if err := validateHeaders(cfg.Headers); err != nil {
+		return fmt.Errorf("配置文件 %s 中的%v", path, err)
+	}
+	if err := cfg.Proxy.validate(); err != nil {
+		return fmt.Errorf("配置文件 %s 中的%v", path, err)
+	}
+	if err := validateTLSMode(cfg.TLS); err != nil {
+		return fmt.Errorf("配置文件 %s 中的%v", path, err)
+	}
+	cas, err := loadCABundle(cfg.CABundle)
+	if err != nil {
+		return fmt.Errorf("配置文件 %s 中的%v", path, err)
+	}
+	for name, profile := range cfg.Profiles {
+		if !validName(name) {
+			return fmt.Errorf("配置文件 %s 中的浏览器配置名称 %q 无效", path, name)
+		}
+		if err := profile.Login.validate(); err != nil {
+			return fmt.Errorf("配置文件 %s 中浏览器配置 %s 的%v", path, name, err)
+		}
+	}
+	appConfig = cfg
+	appCAs = cas
+	return nil
+}
+
+// registerConfigFlag 注册配置文件路径选项
+func registerConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", defaultConfigPath, "配置文件路径")
+}
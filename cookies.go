@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie 截图前写入浏览器的Cookie
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain,omitempty"` // 为空时使用截图URL的主机名；以"."开头时包含子域名
+	Path     string  `json:"path,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	HTTPOnly bool    `json:"httpOnly,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"` // Strict、Lax或None
+	Expires  float64 `json:"expires,omitempty"`  // Unix时间戳(秒)，0表示会话Cookie
+}
+
+// parseCookies 解析Cookie文件内容，以"["开头时按JSON列表解析，否则按Netscape cookies.txt格式解析
+func parseCookies(text string) ([]Cookie, error) {
+	if strings.HasPrefix(strings.TrimSpace(text), "[") {
+		var cookies []Cookie
+		if err := json.Unmarshal([]byte(text), &cookies); err != nil {
+			return nil, fmt.Errorf("解析Cookie JSON失败: %v", err)
+		}
+		return cookies, validateCookies(cookies)
+	}
+	return parseNetscapeCookies(text)
+}
+
+// parseNetscapeCookies 解析Netscape cookies.txt格式：
+// 域名、是否包含子域名、路径、是否仅HTTPS、过期时间、名称、值，以制表符分隔
+func parseNetscapeCookies(text string) ([]Cookie, error) {
+	var cookies []Cookie
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		// 值为空的Cookie行以制表符结尾，不能去掉行尾的制表符
+		line := strings.TrimLeft(strings.TrimRight(scanner.Text(), "\r"), " ")
+		httpOnly := false
+		// curl等工具以#HttpOnly_前缀标记HttpOnly的Cookie
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookies.txt第%d行格式错误: 应有7个以制表符分隔的字段", n)
+		}
+		c := Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+		// 包含子域名的Cookie域名以"."开头
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(c.Domain, ".") {
+			c.Domain = "." + c.Domain
+		}
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookies.txt第%d行的过期时间无效: %s", n, fields[4])
+		}
+		c.Expires = expires
+		cookies = append(cookies, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, validateCookies(cookies)
+}
+
+// validateCookies 检查Cookie的名称和SameSite取值
+func validateCookies(cookies []Cookie) error {
+	for _, c := range cookies {
+		if c.Name == "" {
+			return fmt.Errorf("Cookie名称不能为空")
+		}
+		switch strings.ToLower(c.SameSite) {
+		case "", "strict", "lax", "none":
+		default:
+			return fmt.Errorf("Cookie %s 的SameSite无效: %s", c.Name, c.SameSite)
+		}
+	}
+	return nil
+}
+
+// cookieParams 转换为DevTools的Cookie参数，没有域名的Cookie绑定到截图URL的主机
+func cookieParams(cookies []Cookie, target string) []*network.CookieParam {
+	u, _ := url.Parse(target)
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+		}
+		// 不以"."开头的域名只对该主机生效，通过URL而不是Domain设置
+		if !strings.HasPrefix(c.Domain, ".") && u != nil {
+			host := u.Host
+			if c.Domain != "" {
+				host = c.Domain
+			}
+			scheme := u.Scheme
+			if c.Secure {
+				scheme = "https"
+			}
+			p.URL = scheme + "://" + host
+			p.Domain = ""
+		}
+		if p.Path == "" {
+			p.Path = "/"
+		}
+		switch strings.ToLower(c.SameSite) {
+		case "strict":
+			p.SameSite = network.CookieSameSiteStrict
+		case "lax":
+			p.SameSite = network.CookieSameSiteLax
+		case "none":
+			p.SameSite = network.CookieSameSiteNone
+		}
+		if c.Expires > 0 {
+			sec, frac := math.Modf(c.Expires)
+			t := cdp.TimeSinceEpoch(time.Unix(int64(sec), int64(frac*1e9)))
+			p.Expires = &t
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// cookies 合并会话、cookies.txt和请求中的Cookie，后出现的同名Cookie覆盖先出现的
+func (o CaptureOptions) cookies() ([]Cookie, error) {
+	var cookies []Cookie
+	if o.Session != "" {
+		s, err := loadSession(o.Session)
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, s.Cookies...)
+	}
+	if o.CookiesTxt != "" {
+		parsed, err := parseCookies(o.CookiesTxt)
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, parsed...)
+	}
+	if err := validateCookies(o.Cookies); err != nil {
+		return nil, err
+	}
+	return append(cookies, o.Cookies...), nil
+}
+
+// setCookies 在导航前写入Cookie
+func setCookies(cookies []Cookie, target string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(cookies) == 0 {
+			return nil
+		}
+		if err := network.SetCookies(cookieParams(cookies, target)).Do(ctx); err != nil {
+			return fmt.Errorf("设置Cookie失败: %v", err)
+		}
+		return nil
+	})
+}
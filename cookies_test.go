@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestParseCookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Cookie
+		wantErr bool
+	}{
+		{
+			name: "netscape",
+			in: "# Netscape HTTP Cookie File\n" +
+				"\n" +
+				".example.com\tTRUE\t/\tTRUE\t1700000000\tsid\tabc\n" +
+				"example.org\tFALSE\t/app\tFALSE\t0\tlang\tzh-CN\r\n",
+			want: []Cookie{
+				{Name: "sid", Value: "abc", Domain: ".example.com", Path: "/", Secure: true, Expires: 1700000000},
+				{Name: "lang", Value: "zh-CN", Domain: "example.org", Path: "/app"},
+			},
+		},
+		{
+			name: "include subdomains adds dot",
+			in:   "example.com\tTRUE\t/\tFALSE\t0\ta\t1",
+			want: []Cookie{{Name: "a", Value: "1", Domain: ".example.com", Path: "/"}},
+		},
+		{
+			name: "httponly prefix",
+			in:   "#HttpOnly_example.com\tFALSE\t/\tFALSE\t0\ttoken\tx",
+			want: []Cookie{{Name: "token", Value: "x", Domain: "example.com", Path: "/", HTTPOnly: true}},
+		},
+		{
+			name: "empty value",
+			in:   "example.com\tFALSE\t/\tFALSE\t0\tempty\t",
+			want: []Cookie{{Name: "empty", Domain: "example.com", Path: "/"}},
+		},
+		{
+			name: "json",
+			in:   ` [{"name":"sid","value":"abc","sameSite":"Lax"}] `,
+			want: []Cookie{{Name: "sid", Value: "abc", SameSite: "Lax"}},
+		},
+		{name: "empty file", in: "# only comments\n\n", want: nil},
+		{name: "wrong field count", in: "example.com\tFALSE\t/\tFALSE\t0\tsid", wantErr: true},
+		{name: "bad expires", in: "example.com\tFALSE\t/\tFALSE\tsoon\tsid\tabc", wantErr: true},
+		{name: "empty name", in: "example.com\tFALSE\t/\tFALSE\t0\t\tabc", wantErr: true},
+		{name: "bad json", in: `[{"name":}]`, wantErr: true},
+		{name: "bad samesite", in: `[{"name":"sid","sameSite":"Sometimes"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCookies(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseCookies() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseCookies() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCookieParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie Cookie
+		target string
+		want   network.CookieParam
+	}{
+		{
+			name:   "no domain binds to target host",
+			cookie: Cookie{Name: "sid", Value: "abc"},
+			target: "http://example.com:8080/page",
+			want:   network.CookieParam{Name: "sid", Value: "abc", URL: "http://example.com:8080", Path: "/"},
+		},
+		{
+			name:   "host-only domain",
+			cookie: Cookie{Name: "sid", Domain: "api.example.com", Path: "/v1"},
+			target: "http://example.com/",
+			want:   network.CookieParam{Name: "sid", URL: "http://api.example.com", Path: "/v1"},
+		},
+		{
+			name:   "secure cookie uses https",
+			cookie: Cookie{Name: "sid", Secure: true, HTTPOnly: true},
+			target: "http://example.com/",
+			want:   network.CookieParam{Name: "sid", URL: "https://example.com", Path: "/", Secure: true, HTTPOnly: true},
+		},
+		{
+			name:   "domain cookie keeps domain",
+			cookie: Cookie{Name: "sid", Domain: ".example.com", SameSite: "none"},
+			target: "https://www.example.com/",
+			want:   network.CookieParam{Name: "sid", Domain: ".example.com", Path: "/", SameSite: network.CookieSameSiteNone},
+		},
+		{
+			name:   "samesite strict",
+			cookie: Cookie{Name: "sid", Domain: ".example.com", SameSite: "Strict"},
+			target: "https://example.com/",
+			want:   network.CookieParam{Name: "sid", Domain: ".example.com", Path: "/", SameSite: network.CookieSameSiteStrict},
+		},
+	}
+	for _, tt := range tests {
+		got := cookieParams([]Cookie{tt.cookie}, tt.target)
+		if len(got) != 1 {
+			t.Fatalf("%s: cookieParams() returned %d params", tt.name, len(got))
+		}
+		if !reflect.DeepEqual(*got[0], tt.want) {
+			t.Errorf("%s: cookieParams() = %+v, want %+v", tt.name, *got[0], tt.want)
+		}
+	}
+}
+
+func TestCookieParamsExpires(t *testing.T) {
+	got := cookieParams([]Cookie{{Name: "sid", Expires: 1700000000.5}}, "https://example.com/")
+	if got[0].Expires == nil {
+		t.Fatal("cookieParams() did not set Expires")
+	}
+	want := time.Unix(1700000000, 500000000)
+	if at := got[0].Expires.Time(); !at.Equal(want) {
+		t.Errorf("cookieParams() Expires = %v, want %v", at, want)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// elementRect 元素在文档中的位置(CSS像素)
+type elementRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Hidden bool    `json:"hidden"` // visibility为hidden，占据位置但不显示
+}
+
+// visible 判断元素是否会显示在截图中
+func (r elementRect) visible() bool {
+	return r.Width > 0 && r.Height > 0 && !r.Hidden
+}
+
+// elementRectsScript 返回匹配选择器的所有元素相对于文档左上角的位置
+const elementRectsScript = `(function(selector) {
+	return Array.from(document.querySelectorAll(selector)).map(function(e) {
+		var r = e.getBoundingClientRect();
+		var hidden = getComputedStyle(e).visibility === 'hidden';
+		return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height, hidden: hidden};
+	});
+})(%s)`
+
+// captureElements 按CSS选择器截取元素，跳过不可见的元素(如只在移动端显示的重复导航)；
+// AllMatches为false时只截取第一个可见的元素
+func captureElements(ctx context.Context, opts CaptureOptions) ([][]byte, error) {
+	selector, _ := json.Marshal(opts.Selector)
+	var rects []elementRect
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(elementRectsScript, selector), &rects)); err != nil {
+		return nil, fmt.Errorf("查找元素 %s 失败: %v", opts.Selector, err)
+	}
+	if len(rects) == 0 {
+		return nil, fmt.Errorf("选择器 %s 没有匹配的元素", opts.Selector)
+	}
+	visible := visibleRects(rects, opts.AllMatches)
+	if len(visible) == 0 {
+		return nil, fmt.Errorf("选择器 %s 匹配的%d个元素都不可见", opts.Selector, len(rects))
+	}
+
+	images := make([][]byte, 0, len(visible))
+	for _, r := range visible {
+		// 四周加上边距，但不超出文档左上角
+		pad := float64(opts.Padding)
+		x, y := math.Max(r.X-pad, 0), math.Max(r.Y-pad, 0)
+		clip := &page.Viewport{
+			X:      x,
+			Y:      y,
+			Width:  r.X + r.Width + pad - x,
+			Height: r.Y + r.Height + pad - y,
+			Scale:  1,
+		}
+		// 元素可能位于视口之外，需要截取视口以外的内容
+		data, err := opts.screenshotParams().WithClip(clip).WithCaptureBeyondViewport(true).Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("截取元素 %s 失败: %v", opts.Selector, err)
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// visibleRects 返回可见的元素，all为false时只返回第一个
+func visibleRects(rects []elementRect, all bool) []elementRect {
+	var visible []elementRect
+	for _, r := range rects {
+		if !r.visible() {
+			continue
+		}
+		visible = append(visible, r)
+		if !all {
+			break
+		}
+	}
+	return visible
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVisibleRects(t *testing.T) {
+	hiddenHeader := elementRect{X: 0, Y: 0, Width: 0, Height: 0}
+	invisible := elementRect{X: 0, Y: 10, Width: 100, Height: 20, Hidden: true}
+	first := elementRect{X: 0, Y: 50, Width: 300, Height: 80}
+	second := elementRect{X: 0, Y: 200, Width: 300, Height: 80}
+	tests := []struct {
+		name  string
+		rects []elementRect
+		all   bool
+		want  []elementRect
+	}{
+		{name: "first visible", rects: []elementRect{hiddenHeader, invisible, first, second}, want: []elementRect{first}},
+		{name: "all visible", rects: []elementRect{first, hiddenHeader, second, invisible}, all: true, want: []elementRect{first, second}},
+		{name: "none visible", rects: []elementRect{hiddenHeader, invisible}, all: true, want: nil},
+		{name: "no matches", rects: nil, want: nil},
+	}
+	for _, tt := range tests {
+		if got := visibleRects(tt.rects, tt.all); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: visibleRects() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
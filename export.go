@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestEntry 导出清单中单个URL的记录
+type manifestEntry struct {
+	Index       int              `json:"index"`
+	URL         string           `json:"url"`
+	FinalURL    string           `json:"finalUrl,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	HTTPStatus  int              `json:"httpStatus,omitempty"`
+	Status      string           `json:"status"`
+	Error       string           `json:"error,omitempty"`
+	Proxy       string           `json:"proxy,omitempty"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+	CertErrors  []string         `json:"certificateErrors,omitempty"`
+	Filename    string           `json:"filename,omitempty"`
+	Filenames   []string         `json:"filenames,omitempty"` // 有多张图片时的全部文件名，第一项与Filename相同
+	CapturedAt  *time.Time       `json:"capturedAt,omitempty"`
+}
+
+// exportManifest 导出包中的manifest.json
+type exportManifest struct {
+	JobID        string          `json:"jobId"`
+	Status       string          `json:"status"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	FinishedAt   *time.Time      `json:"finishedAt,omitempty"`
+	ExportedAt   time.Time       `json:"exportedAt"`
+	Total        int             `json:"total"`
+	SuccessCount int             `json:"successCount"`
+	FailureCount int             `json:"failureCount"`
+	Results      []manifestEntry `json:"results"`
+}
+
+// exportFileNames 为任务中每个成功的截图分配导出包内唯一的文件名，以结果序号为键；
+// 有多张图片时按顺序为每一张分配一个带_partN后缀的文件名
+func exportFileNames(j *Job, s JobStatus) map[int][]string {
+	names := make(map[int][]string)
+	used := make(map[string]bool)
+	for _, r := range s.Results {
+		exts := j.ImageExts(r.Index)
+		if len(exts) == 0 {
+			continue
+		}
+		base := strings.TrimSuffix(filepath.Base(r.File), exts[0])
+		if r.File == "" || len(exts) > 1 {
+			// 多张图片时保存的文件名已带有序号，重新按模板生成不含序号的名称
+			info := SaveInfo{URL: r.URL, Index: r.Index, JobID: j.ID, Time: s.CreatedAt}
+			if r.CapturedAt != nil {
+				info.Time = *r.CapturedAt
+			}
+			base = filepath.Base(renderFileName(appConfig.FileNameTemplate, info))
+		}
+		for i, ext := range exts {
+			partBase := base
+			if len(exts) > 1 {
+				partBase = fmt.Sprintf("%s_part%d", base, i+1)
+			}
+			name := partBase + ext
+			for n := 1; used[name]; n++ {
+				name = fmt.Sprintf("%s_%d%s", partBase, n, ext)
+			}
+			used[name] = true
+			names[r.Index] = append(names[r.Index], name)
+		}
+	}
+	return names
+}
+
+// writeJobZip 将任务的全部截图、manifest.json和引用这些图片的report.html以ZIP格式写入w
+func writeJobZip(w io.Writer, j *Job) error {
+	s := j.Snapshot(true)
+	names := exportFileNames(j, s)
+
+	zw := zip.NewWriter(w)
+	manifest := exportManifest{
+		JobID:        s.ID,
+		Status:       s.Status,
+		CreatedAt:    s.CreatedAt,
+		FinishedAt:   s.FinishedAt,
+		ExportedAt:   time.Now(),
+		Total:        s.Total,
+		SuccessCount: s.SuccessCount,
+		FailureCount: s.FailureCount,
+		Results:      make([]manifestEntry, 0, len(s.Results)),
+	}
+	for _, r := range s.Results {
+		entry := manifestEntry{
+			Index:       r.Index,
+			URL:         r.URL,
+			FinalURL:    r.FinalURL,
+			Title:       r.Title,
+			HTTPStatus:  r.HTTPStatus,
+			Status:      r.Status,
+			Error:       r.Error,
+			Proxy:       r.Proxy,
+			Certificate: r.Certificate,
+			CertErrors:  r.CertErrors,
+			CapturedAt:  r.CapturedAt,
+		}
+		if files, ok := names[r.Index]; ok {
+			for i := range files {
+				// 逐张读取，已自动保存的截图从文件读取，避免整个任务的图片同时占用内存
+				data, ok := j.PartImage(r.Index, i+1)
+				if !ok {
+					return fmt.Errorf("读取第%d个结果的截图失败", r.Index+1)
+				}
+				// 图片本身已压缩，直接存储即可
+				fw, err := zw.CreateHeader(&zip.FileHeader{Name: files[i], Method: zip.Store, Modified: time.Now()})
+				if err != nil {
+					return err
+				}
+				if _, err := fw.Write(data); err != nil {
+					return err
+				}
+			}
+			entry.Filename = files[0]
+			if len(files) > 1 {
+				entry.Filenames = files
+			}
+		}
+		manifest.Results = append(manifest.Results, entry)
+	}
+
+	fw, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	fw, err = zw.Create("report.html")
+	if err != nil {
+		return err
+	}
+	if err := writeJobReport(fw, j, names); err != nil {
+		return err
+	}
+	return zw.Close()
+}
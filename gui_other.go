@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "fmt"
+
+// openWindow 非Windows平台没有WebView2，只打印页面地址
+func openWindow(addr string) {
+	fmt.Printf("当前平台不支持WebView窗口，请在浏览器中打开: http://%s\n", addr)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jchv/go-webview2"
+)
+
+// openWindow 创建WebView窗口加载本地服务器页面，并阻塞直到窗口关闭
+func openWindow(addr string) {
+	// 创建WebView窗口
+	w := webview2.NewWithOptions(webview2.WebViewOptions{
+		Debug: true,
+	})
+	defer w.Destroy()
+
+	// 设置窗口标题
+	w.SetTitle("WebCut-网页快照")
+
+	// 加载本地服务器的HTML页面
+	w.Navigate(fmt.Sprintf("http://%s", addr))
+
+	// 运行WebView主循环
+	w.Run()
+}
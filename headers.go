@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// requestHeaders 合并配置文件与请求参数后实际使用的请求头
+type requestHeaders struct {
+	Headers        map[string]string
+	UserAgent      string
+	AcceptLanguage string
+}
+
+// validateHeaders 检查请求头名称和值，避免注入换行
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("无效的请求头名称 %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("请求头 %s 的值不能包含换行", name)
+		}
+	}
+	return nil
+}
+
+// requestHeaders 以配置文件中的设置为默认值，请求中的同名请求头和UA、语言覆盖默认值
+func (o CaptureOptions) requestHeaders() requestHeaders {
+	h := requestHeaders{
+		Headers:        make(map[string]string),
+		UserAgent:      appConfig.UserAgent,
+		AcceptLanguage: appConfig.AcceptLanguage,
+	}
+	for name, value := range appConfig.Headers {
+		h.Headers[name] = value
+	}
+	for name, value := range o.Headers {
+		h.Headers[name] = value
+	}
+	if o.UserAgent != "" {
+		h.UserAgent = o.UserAgent
+	}
+	if o.AcceptLanguage != "" {
+		h.AcceptLanguage = o.AcceptLanguage
+	}
+	return h
+}
+
+// applyHeaders 在导航前设置额外的请求头、UA和Accept-Language。
+// 未指定UA时使用设备预设的UA；只指定语言时沿用浏览器默认的UA
+func applyHeaders(h requestHeaders, vp DevicePreset) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(h.Headers) > 0 {
+			headers := make(network.Headers, len(h.Headers))
+			for name, value := range h.Headers {
+				headers[name] = value
+			}
+			if err := network.SetExtraHTTPHeaders(headers).Do(ctx); err != nil {
+				return fmt.Errorf("设置请求头失败: %v", err)
+			}
+		}
+
+		ua := h.UserAgent
+		if ua == "" {
+			ua = vp.UserAgent
+		}
+		if ua == "" && h.AcceptLanguage != "" {
+			_, _, _, defaultUA, _, err := browser.GetVersion().Do(ctx)
+			if err != nil {
+				return fmt.Errorf("获取浏览器UA失败: %v", err)
+			}
+			ua = defaultUA
+		}
+		// UA为空时恢复浏览器默认值，同一标签页切换设备时不会沿用上一个设备的UA
+		params := emulation.SetUserAgentOverride(ua)
+		if h.AcceptLanguage != "" {
+			params = params.WithAcceptLanguage(h.AcceptLanguage)
+		}
+		if err := params.Do(ctx); err != nil {
+			return fmt.Errorf("设置UA失败: %v", err)
+		}
+		return nil
+	})
+}
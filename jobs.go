@@ -0,0 +1,734 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 任务状态
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobPaused    = "paused"
+	JobCancelled = "cancelled"
+	JobCompleted = "completed"
+)
+
+// 单个URL的结果状态
+const (
+	ResultPending   = "pending"
+	ResultSuccess   = "success"
+	ResultFailed    = "failed"
+	ResultCancelled = "cancelled"
+)
+
+// 最多保留的任务数，超出后丢弃最早完成的任务
+const maxRetainedJobs = 50
+
+// 内存中保留的截图总大小上限，超出后从最早结束的任务开始释放；已自动保存的截图不占用内存
+const maxRetainedImageBytes = 512 << 20
+
+// JobRequest 创建批量任务的请求参数
+type JobRequest struct {
+	URLs []string `json:"urls"`
+	CaptureOptions
+}
+
+// validate 检查URL列表和截图参数
+func (r JobRequest) validate() error {
+	for _, u := range r.URLs {
+		if err := validateCaptureURL(u); err != nil {
+			return err
+		}
+	}
+	return r.CaptureOptions.validate()
+}
+
+// JobResult 单个URL的截图结果
+type JobResult struct {
+	Index       int              `json:"index"`
+	URL         string           `json:"url"`
+	FinalURL    string           `json:"finalUrl,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	HTTPStatus  int              `json:"httpStatus,omitempty"`
+	Status      string           `json:"status"`
+	Error       string           `json:"error,omitempty"`
+	FailedStep  int              `json:"failedStep,omitempty"`        // 交互步骤失败时为失败的步骤序号(从1开始)
+	Proxy       string           `json:"proxy,omitempty"`             // 使用的代理，直连时为空
+	Certificate *CertificateInfo `json:"certificate,omitempty"`       // 主文档的服务器证书
+	CertErrors  []string         `json:"certificateErrors,omitempty"` // 严格模式下因证书错误加载失败的其他请求
+	ImageURL    string           `json:"imageUrl,omitempty"`
+	PartCount   int              `json:"partCount,omitempty"` // 多个元素或分段长图的图片数，第n张可通过imageUrl?part=n获取
+	MimeType    string           `json:"mimeType,omitempty"`
+	File        string           `json:"file,omitempty"`
+	Files       []string         `json:"files,omitempty"` // 有多张图片时的全部文件
+	SaveError   string           `json:"saveError,omitempty"`
+	CapturedAt  *time.Time       `json:"capturedAt,omitempty"`
+
+	image  []byte
+	parts  [][]byte
+	onDisk bool // 截图已自动保存，不再保留在内存中，需要时从File/Files读取
+}
+
+// memImages 返回保留在内存中的全部图片
+func (r *JobResult) memImages() [][]byte {
+	if len(r.parts) > 0 {
+		return r.parts
+	}
+	if r.image != nil {
+		return [][]byte{r.image}
+	}
+	return nil
+}
+
+// savedFiles 返回自动保存的全部文件
+func (r *JobResult) savedFiles() []string {
+	if len(r.Files) > 0 {
+		return r.Files
+	}
+	return []string{r.File}
+}
+
+// imageCount 返回结果的图片数，没有图片或图片已释放时为0
+func (r *JobResult) imageCount() int {
+	if r.onDisk {
+		return len(r.savedFiles())
+	}
+	return len(r.memImages())
+}
+
+// JobStatus 任务状态快照，用于JSON输出
+type JobStatus struct {
+	ID           string      `json:"id"`
+	Status       string      `json:"status"`
+	Total        int         `json:"total"`
+	Completed    int         `json:"completed"`
+	SuccessCount int         `json:"successCount"`
+	FailureCount int         `json:"failureCount"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	StartedAt    *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time  `json:"finishedAt,omitempty"`
+	Results      []JobResult `json:"results,omitempty"`
+}
+
+// Job 一次批量截图任务，拥有独立的进度计数和结果
+type Job struct {
+	ID     string
+	req    JobRequest
+	ctx    context.Context // 取消任务时被取消，传递给正在进行的截图
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	status       string
+	results      []*JobResult
+	completed    int
+	successCount int
+	failureCount int
+	createdAt    time.Time
+	startedAt    time.Time
+	finishedAt   time.Time
+	resume       chan struct{} // 暂停期间非nil，继续时关闭
+	done         chan struct{} // 任务结束后关闭
+}
+
+// Done 返回任务结束时关闭的通道
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Snapshot 返回任务当前状态，withResults为false时不包含逐个URL的结果
+func (j *Job) Snapshot(withResults bool) JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := JobStatus{
+		ID:           j.ID,
+		Status:       j.status,
+		Total:        len(j.results),
+		Completed:    j.completed,
+		SuccessCount: j.successCount,
+		FailureCount: j.failureCount,
+		CreatedAt:    j.createdAt,
+	}
+	if !j.startedAt.IsZero() {
+		t := j.startedAt
+		s.StartedAt = &t
+	}
+	if !j.finishedAt.IsZero() {
+		t := j.finishedAt
+		s.FinishedAt = &t
+	}
+	if withResults {
+		s.Results = make([]JobResult, len(j.results))
+		for i, r := range j.results {
+			s.Results[i] = *r
+		}
+	}
+	return s
+}
+
+// Image 返回指定序号URL的截图数据
+func (j *Job) Image(index int) ([]byte, bool) {
+	return j.PartImage(index, 1)
+}
+
+// Images 返回结果的全部图片，多个元素或分段长图时按顺序返回每一张
+func (j *Job) Images(index int) [][]byte {
+	var images [][]byte
+	for n := 1; n <= j.ImageCount(index); n++ {
+		data, ok := j.PartImage(index, n)
+		if !ok {
+			return nil
+		}
+		images = append(images, data)
+	}
+	return images
+}
+
+// ImageCount 返回结果的图片数
+func (j *Job) ImageCount(index int) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if index < 0 || index >= len(j.results) {
+		return 0
+	}
+	return j.results[index].imageCount()
+}
+
+// ImageExts 返回结果中各图片的扩展名，已保存的图片按文件名判断，不读取文件；文件已被删除时返回nil
+func (j *Job) ImageExts(index int) []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if index < 0 || index >= len(j.results) {
+		return nil
+	}
+	r := j.results[index]
+	var exts []string
+	if r.onDisk {
+		for _, f := range r.savedFiles() {
+			if _, err := os.Stat(f); err != nil {
+				return nil
+			}
+			exts = append(exts, filepath.Ext(f))
+		}
+		return exts
+	}
+	for _, data := range r.memImages() {
+		exts = append(exts, imageExt(data))
+	}
+	return exts
+}
+
+// PartImage 返回结果中的第n张图片，n从1开始；已自动保存的图片从文件读取
+func (j *Job) PartImage(index, n int) ([]byte, bool) {
+	j.mu.Lock()
+	if index < 0 || index >= len(j.results) || n < 1 || n > j.results[index].imageCount() {
+		j.mu.Unlock()
+		return nil, false
+	}
+	r := j.results[index]
+	if !r.onDisk {
+		defer j.mu.Unlock()
+		return r.memImages()[n-1], true
+	}
+	file := r.savedFiles()[n-1]
+	j.mu.Unlock()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("读取截图文件 %s 失败: %v\n", file, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// memoryBytes 返回任务保留在内存中的截图总大小
+func (j *Job) memoryBytes() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	total := 0
+	for _, r := range j.results {
+		for _, data := range r.memImages() {
+			total += len(data)
+		}
+	}
+	return total
+}
+
+// releaseImages 释放内存中的截图，已自动保存的截图仍可从文件读取，返回释放的字节数
+func (j *Job) releaseImages() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	freed := 0
+	for _, r := range j.results {
+		images := r.memImages()
+		if len(images) == 0 {
+			continue
+		}
+		for _, data := range images {
+			freed += len(data)
+		}
+		r.image, r.parts = nil, nil
+		r.ImageURL = ""
+		r.PartCount = 0
+	}
+	return freed
+}
+
+// run 并发截取任务中的所有URL，并发数与浏览器池容量一致
+func (j *Job) run() {
+	j.mu.Lock()
+	if j.status == JobQueued {
+		j.status = JobRunning
+	}
+	j.startedAt = time.Now()
+	j.mu.Unlock()
+
+	semaphore := make(chan struct{}, browserPool.Capacity())
+	var wg sync.WaitGroup
+	for i, url := range j.req.URLs {
+		// 暂停时停止派发，取消后不再派发剩余的URL
+		if !j.acquireSlot(semaphore) {
+			break
+		}
+		wg.Add(1)
+		go func(index int, url string) {
+			defer wg.Done()
+			defer func() { <-semaphore }() // 释放信号量
+
+			// 轮换代理时按序号选择代理，结果中记录实际使用的代理
+			opts := j.req.CaptureOptions
+			opts.Proxy = opts.proxy().at(index)
+
+			// 捕获截图 - 批量任务默认超时时间为60秒
+			res, err := captureScreenshot(j.ctx, url, opts)
+			var files []string
+			var saveErr error
+			if err != nil {
+				fmt.Printf("URL %s 截图失败: %v\n", url, err)
+			} else {
+				fmt.Printf("URL %s 截图成功\n", url)
+				files, saveErr = autoSaveResult(res, SaveInfo{URL: url, Index: index, JobID: j.ID, Time: time.Now()})
+			}
+			// 成功时记录标签页实际使用的代理，失败时结果为空，按参数推断
+			proxy := opts.proxyLabel()
+			if res != nil {
+				proxy = res.Proxy
+			}
+			j.record(index, proxy, res, files, saveErr, err)
+		}(i, url)
+	}
+	wg.Wait()
+
+	j.mu.Lock()
+	if j.ctx.Err() != nil {
+		j.status = JobCancelled
+		// 未派发的URL标记为已取消
+		for _, r := range j.results {
+			if r.Status == ResultPending {
+				r.Status = ResultCancelled
+			}
+		}
+	} else {
+		j.status = JobCompleted
+	}
+	j.finishedAt = time.Now()
+	j.resume = nil
+	j.mu.Unlock()
+	j.cancel()
+	close(j.done)
+}
+
+// acquireSlot 获取信号量；等待期间任务被暂停时归还信号量，继续后重新获取。任务被取消时返回false
+func (j *Job) acquireSlot(semaphore chan struct{}) bool {
+	for {
+		if !j.waitRunnable() {
+			return false
+		}
+		select {
+		case semaphore <- struct{}{}:
+		case <-j.ctx.Done():
+			return false
+		}
+		j.mu.Lock()
+		paused := j.resume != nil
+		j.mu.Unlock()
+		if !paused && j.ctx.Err() == nil {
+			return true
+		}
+		<-semaphore
+	}
+}
+
+// waitRunnable 任务暂停时阻塞直到继续，任务被取消时返回false
+func (j *Job) waitRunnable() bool {
+	for {
+		j.mu.Lock()
+		resume := j.resume
+		j.mu.Unlock()
+		if resume == nil {
+			return j.ctx.Err() == nil
+		}
+		select {
+		case <-resume:
+		case <-j.ctx.Done():
+			return false
+		}
+	}
+}
+
+// Pause 暂停派发新的URL，正在进行的截图会继续完成
+func (j *Job) Pause() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.status {
+	case JobQueued, JobRunning:
+		j.status = JobPaused
+		j.resume = make(chan struct{})
+		return nil
+	case JobPaused:
+		return nil
+	default:
+		return fmt.Errorf("任务已结束，无法暂停")
+	}
+}
+
+// Resume 继续派发暂停的任务
+func (j *Job) Resume() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.status {
+	case JobPaused:
+		j.status = JobRunning
+		close(j.resume)
+		j.resume = nil
+		return nil
+	case JobQueued, JobRunning:
+		return nil
+	default:
+		return fmt.Errorf("任务已结束，无法继续")
+	}
+}
+
+// Cancel 取消任务，中止正在进行的截图并丢弃未派发的URL
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// record 保存单个URL的截图结果并更新进度
+func (j *Job) record(index int, proxy string, res *CaptureResult, files []string, saveErr, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	r := j.results[index]
+	now := time.Now()
+	r.CapturedAt = &now
+	r.Proxy = proxy
+	if err != nil && j.ctx.Err() != nil {
+		// 截图因任务取消而中止，不计入失败
+		r.Status = ResultCancelled
+		r.Error = "任务已取消"
+		return
+	}
+	if err != nil {
+		r.Status = ResultFailed
+		r.Error = err.Error()
+		var stepErr *StepError
+		if errors.As(err, &stepErr) {
+			r.FailedStep = stepErr.Index
+		}
+		var certErr *CertificateError
+		if errors.As(err, &certErr) {
+			r.Certificate = certErr.Certificate
+		}
+		j.failureCount++
+	} else {
+		r.Status = ResultSuccess
+		if saveErr == nil && len(files) > 0 {
+			// 已写入磁盘的截图不再保留在内存中
+			r.onDisk = true
+		} else {
+			r.image = res.Image
+			r.parts = res.Images
+		}
+		r.PartCount = len(res.Images)
+		r.MimeType = res.MimeType
+		r.FinalURL = res.FinalURL
+		r.Title = res.Title
+		r.HTTPStatus = res.StatusCode
+		r.Certificate = res.Certificate
+		r.CertErrors = res.CertificateErrors
+		if len(files) > 0 {
+			r.File = files[0]
+		}
+		if len(files) > 1 {
+			r.Files = files
+		}
+		if saveErr != nil {
+			r.SaveError = saveErr.Error()
+		}
+		r.ImageURL = fmt.Sprintf("/api/jobs/%s/results/%d/image", j.ID, index)
+		j.successCount++
+	}
+	j.completed++
+}
+
+// JobManager 管理所有批量任务
+type JobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	list []*Job // 按创建顺序排列
+}
+
+// NewJobManager 创建任务管理器
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+var jobManager = NewJobManager()
+
+// newJobID 生成随机任务ID
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Create 创建任务并在后台开始执行
+func (m *JobManager) Create(req JobRequest) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{
+		ID:        newJobID(),
+		req:       req,
+		ctx:       ctx,
+		cancel:    cancel,
+		status:    JobQueued,
+		results:   make([]*JobResult, len(req.URLs)),
+		createdAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	for i, url := range req.URLs {
+		j.results[i] = &JobResult{Index: i, URL: url, Status: ResultPending}
+	}
+
+	m.mu.Lock()
+	m.jobs[j.ID] = j
+	m.list = append(m.list, j)
+	m.prune()
+	m.mu.Unlock()
+
+	go func() {
+		j.run()
+		m.mu.Lock()
+		m.prune()
+		m.mu.Unlock()
+	}()
+	return j
+}
+
+// prune 丢弃超出保留数量的已完成任务，并在内存中的截图超出上限时释放最早结束的任务的截图，
+// 调用方需持有m.mu
+func (m *JobManager) prune() {
+	for i := 0; len(m.list) > maxRetainedJobs && i < len(m.list); {
+		j := m.list[i]
+		select {
+		case <-j.done:
+			delete(m.jobs, j.ID)
+			m.list = append(m.list[:i], m.list[i+1:]...)
+		default:
+			i++
+		}
+	}
+
+	total := 0
+	for _, j := range m.list {
+		total += j.memoryBytes()
+	}
+	for _, j := range m.list {
+		if total <= maxRetainedImageBytes {
+			break
+		}
+		select {
+		case <-j.done:
+			total -= j.releaseImages()
+		default:
+		}
+	}
+}
+
+// Get 按ID查找任务
+func (m *JobManager) Get(id string) *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id]
+}
+
+// List 按创建顺序返回所有任务
+func (m *JobManager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Job(nil), m.list...)
+}
+
+// Latest 返回最近创建的任务
+func (m *JobManager) Latest() *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.list) == 0 {
+		return nil
+	}
+	return m.list[len(m.list)-1]
+}
+
+// writeJSON 以JSON格式输出响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError 以JSON格式输出错误
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// jobFromRequest 根据路径中的{id}查找任务，找不到时写入404
+func jobFromRequest(w http.ResponseWriter, r *http.Request) *Job {
+	j := jobManager.Get(r.PathValue("id"))
+	if j == nil {
+		writeJSONError(w, http.StatusNotFound, "任务不存在")
+	}
+	return j
+}
+
+// registerJobHandlers 注册批量任务API
+func registerJobHandlers() {
+	// 创建任务
+	http.HandleFunc("POST /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		if len(req.URLs) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "URL列表为空")
+			return
+		}
+		if err := req.validate(); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		j := jobManager.Create(req)
+		writeJSON(w, http.StatusCreated, j.Snapshot(false))
+	})
+
+	// 列出所有任务
+	http.HandleFunc("GET /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		jobs := jobManager.List()
+		list := make([]JobStatus, len(jobs))
+		for i, j := range jobs {
+			list[i] = j.Snapshot(false)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": list})
+	})
+
+	// 查询任务状态和逐个URL的结果
+	http.HandleFunc("GET /api/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if j := jobFromRequest(w, r); j != nil {
+			writeJSON(w, http.StatusOK, j.Snapshot(true))
+		}
+	})
+
+	// 控制任务：取消、暂停、继续
+	http.HandleFunc("POST /api/jobs/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if j := jobFromRequest(w, r); j != nil {
+			j.Cancel()
+			writeJSON(w, http.StatusOK, j.Snapshot(false))
+		}
+	})
+	http.HandleFunc("POST /api/jobs/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		if j := jobFromRequest(w, r); j != nil {
+			if err := j.Pause(); err != nil {
+				writeJSONError(w, http.StatusConflict, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, j.Snapshot(false))
+		}
+	})
+	http.HandleFunc("POST /api/jobs/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		if j := jobFromRequest(w, r); j != nil {
+			if err := j.Resume(); err != nil {
+				writeJSONError(w, http.StatusConflict, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, j.Snapshot(false))
+		}
+	})
+
+	// 获取单个URL的截图
+	http.HandleFunc("GET /api/jobs/{id}/results/{index}/image", func(w http.ResponseWriter, r *http.Request) {
+		j := jobFromRequest(w, r)
+		if j == nil {
+			return
+		}
+		index, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "无效的结果序号")
+			return
+		}
+		data, ok := j.Image(index)
+		if v := r.URL.Query().Get("part"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "无效的图片序号")
+				return
+			}
+			data, ok = j.PartImage(index, n)
+		}
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "截图不存在或已从内存中释放")
+			return
+		}
+		w.Header().Set("Content-Type", http.DetectContentType(data))
+		w.Write(data)
+	})
+
+	// 生成独立的HTML报告，图片以data URI内嵌
+	http.HandleFunc("GET /api/jobs/{id}/report.html", func(w http.ResponseWriter, r *http.Request) {
+		j := jobFromRequest(w, r)
+		if j == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Query().Get("download") != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="webcut_%s.html"`, j.ID))
+		}
+		if err := writeJobReport(w, j, nil); err != nil {
+			fmt.Printf("生成任务 %s 的报告失败: %v\n", j.ID, err)
+		}
+	})
+
+	// 导出任务的全部截图和清单
+	http.HandleFunc("GET /api/jobs/{id}/export.zip", func(w http.ResponseWriter, r *http.Request) {
+		j := jobFromRequest(w, r)
+		if j == nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="webcut_%s.zip"`, j.ID))
+		if err := writeJobZip(w, j); err != nil {
+			// 响应头已发送，只能记录错误
+			fmt.Printf("导出任务 %s 失败: %v\n", j.ID, err)
+		}
+	})
+}
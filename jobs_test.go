@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestJob() *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Job{ctx: ctx, cancel: cancel, status: JobRunning, done: make(chan struct{})}
+}
+
+func TestAcquireSlotWaitsWhilePaused(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish func(j *Job) // 暂停期间归还名额后对任务的操作
+		want   bool
+	}{
+		{name: "resume", finish: func(j *Job) { j.Resume() }, want: true},
+		{name: "cancel", finish: func(j *Job) { j.Cancel() }, want: false},
+	}
+	for _, tt := range tests {
+		j := newTestJob()
+		semaphore := make(chan struct{}, 1)
+		semaphore <- struct{}{} // 名额已被占用，派发循环阻塞在信号量上
+
+		got := make(chan bool, 1)
+		go func() { got <- j.acquireSlot(semaphore) }()
+		time.Sleep(20 * time.Millisecond)
+		if err := j.Pause(); err != nil {
+			t.Fatal(err)
+		}
+		<-semaphore // 正在进行的截图完成，归还名额
+		select {
+		case ok := <-got:
+			t.Fatalf("%s: acquireSlot() = %v while the job is paused", tt.name, ok)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if len(semaphore) != 0 {
+			t.Fatalf("%s: paused job is holding a slot", tt.name)
+		}
+
+		tt.finish(j)
+		select {
+		case ok := <-got:
+			if ok != tt.want {
+				t.Errorf("%s: acquireSlot() = %v, want %v", tt.name, ok, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: acquireSlot() did not return", tt.name)
+		}
+		j.cancel()
+	}
+}
+
+func TestJobImagesFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\npart")
+	files := []string{filepath.Join(dir, "a_1.png"), filepath.Join(dir, "a_2.png")}
+	for _, f := range files {
+		if err := os.WriteFile(f, png, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j := newTestJob()
+	j.results = []*JobResult{
+		{Index: 0, Status: ResultSuccess, File: files[0], Files: files, onDisk: true},
+		{Index: 1, Status: ResultSuccess, File: filepath.Join(dir, "deleted.png"), onDisk: true},
+		{Index: 2, Status: ResultSuccess, image: png},
+		{Index: 3, Status: ResultFailed},
+	}
+	tests := []struct {
+		index int
+		count int
+		exts  []string
+	}{
+		{index: 0, count: 2, exts: []string{".png", ".png"}},
+		{index: 1, count: 1, exts: nil},
+		{index: 2, count: 1, exts: []string{".png"}},
+		{index: 3, count: 0, exts: nil},
+		{index: 4, count: 0, exts: nil},
+	}
+	for _, tt := range tests {
+		if got := j.ImageCount(tt.index); got != tt.count {
+			t.Errorf("ImageCount(%d) = %d, want %d", tt.index, got, tt.count)
+		}
+		if got := j.ImageExts(tt.index); !reflect.DeepEqual(got, tt.exts) {
+			t.Errorf("ImageExts(%d) = %q, want %q", tt.index, got, tt.exts)
+		}
+	}
+	if data, ok := j.PartImage(0, 2); !ok || !bytes.Equal(data, png) {
+		t.Errorf("PartImage(0, 2) = %q, %v, want the saved file", data, ok)
+	}
+	if _, ok := j.Image(1); ok {
+		t.Error("Image(1) succeeded for a deleted file")
+	}
+	if j.memoryBytes() != len(png) {
+		t.Errorf("memoryBytes() = %d, want %d", j.memoryBytes(), len(png))
+	}
+}
+
+func TestJobManagerReleasesImagesOverLimit(t *testing.T) {
+	big := make([]byte, maxRetainedImageBytes/3+1)
+	m := NewJobManager()
+	var jobs []*Job
+	for i := 0; i < 3; i++ {
+		j := newTestJob()
+		j.results = []*JobResult{{Index: 0, Status: ResultSuccess, image: big, ImageURL: "/x"}}
+		close(j.done)
+		jobs = append(jobs, j)
+	}
+	m.list = jobs
+	m.prune()
+	for i, want := range []bool{false, true, true} {
+		if _, ok := jobs[i].Image(0); ok != want {
+			t.Errorf("job %d kept image = %v, want %v", i, ok, want)
+		}
+	}
+	if s := jobs[0].Snapshot(true); s.Results[0].ImageURL != "" {
+		t.Errorf("released result still has imageUrl %q", s.Results[0].ImageURL)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 单次对比截图允许的视口数量上限
+const maxMatrixViewports = 20
+
+// MatrixRequest 多视口对比截图请求，viewports中的每一项可以是对象或字符串简写
+type MatrixRequest struct {
+	URL       string            `json:"url"`
+	Viewports []ViewportOptions `json:"viewports"`
+	CaptureOptions
+}
+
+// validate 检查URL和截图参数
+func (r MatrixRequest) validate() error {
+	if err := validateCaptureURL(r.URL); err != nil {
+		return err
+	}
+	return r.CaptureOptions.validate()
+}
+
+// MatrixShot 对比截图中单个视口的结果
+type MatrixShot struct {
+	Viewport DevicePreset
+	Result   *CaptureResult
+	Err      error
+}
+
+// captureMatrix 在同一个标签页中依次按各个视口加载URL并截图。
+// 参数错误或无法获取标签页时返回error，单个视口的失败记录在对应的MatrixShot中
+func captureMatrix(ctx context.Context, url string, opts CaptureOptions, viewports []ViewportOptions) ([]MatrixShot, error) {
+	if len(viewports) == 0 {
+		return nil, errors.New("视口列表为空")
+	}
+	if len(viewports) > maxMatrixViewports {
+		return nil, fmt.Errorf("视口数量不能超过%d个", maxMatrixViewports)
+	}
+	shots := make([]MatrixShot, len(viewports))
+	for i := range viewports {
+		vp, err := viewports[i].resolve()
+		if err != nil {
+			return nil, err
+		}
+		shots[i].Viewport = vp
+	}
+
+	lease, err := acquireLease(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release()
+
+	for i := range shots {
+		switch {
+		case ctx.Err() != nil:
+			shots[i].Err = errors.New("已取消")
+		case lease.BrowserLost():
+			shots[i].Err = errors.New("浏览器进程已退出")
+		default:
+			shots[i].Result, shots[i].Err = capturePage(ctx, lease, url, opts, shots[i].Viewport)
+		}
+	}
+	return shots, nil
+}
+
+// registerMatrixHandlers 注册多视口对比截图的HTTP接口
+func registerMatrixHandlers() {
+	http.HandleFunc("POST /api/matrix", func(w http.ResponseWriter, r *http.Request) {
+		var req MatrixRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON format: %v", err))
+			return
+		}
+		if err := req.validate(); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Timeout <= 0 {
+			req.Timeout = 30
+		}
+
+		shots, err := captureMatrix(r.Context(), req.URL, req.CaptureOptions, req.Viewports)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		now := time.Now()
+		results := make([]map[string]interface{}, len(shots))
+		for i, shot := range shots {
+			vp := shot.Viewport
+			item := map[string]interface{}{
+				"device": vp.Name,
+				"label":  vp.label(),
+				"width":  vp.Width,
+				"height": vp.Height,
+				"scale":  vp.Scale,
+				"mobile": vp.Mobile,
+			}
+			if shot.Err != nil {
+				item["error"] = shot.Err.Error()
+				results[i] = item
+				continue
+			}
+			res := shot.Result
+			item["base64Image"] = base64.StdEncoding.EncodeToString(res.Image)
+			item["mimeType"] = res.MimeType
+			item["finalUrl"] = res.FinalURL
+			if res.Proxy != "" {
+				item["proxy"] = res.Proxy
+			}
+			if res.Certificate != nil {
+				item["certificate"] = res.Certificate
+			}
+			if len(res.CertificateErrors) > 0 {
+				item["certificateErrors"] = res.CertificateErrors
+			}
+			item["title"] = res.Title
+			item["httpStatus"] = res.StatusCode
+			files, saveErr := autoSaveResult(res, SaveInfo{URL: req.URL, Index: i, Viewport: vp.label(), Time: now})
+			if saveErr != nil {
+				item["saveError"] = saveErr.Error()
+			} else if len(files) > 0 {
+				item["file"] = strings.Join(files, ", ")
+			}
+			results[i] = item
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"url":     req.URL,
+			"results": results,
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// PDF输出的MIME类型
+const mimePDF = "application/pdf"
+
+// 未指定时的纸张和页边距
+const (
+	defaultPaperSize = "A4"
+	defaultPDFMargin = 10.0 // 毫米
+)
+
+// paperSizes 支持的纸张尺寸(英寸，纵向)
+var paperSizes = map[string][2]float64{
+	"A3":      {11.69, 16.54},
+	"A4":      {8.27, 11.69},
+	"A5":      {5.83, 8.27},
+	"LETTER":  {8.5, 11},
+	"LEGAL":   {8.5, 14},
+	"TABLOID": {11, 17},
+}
+
+// PDFOptions 打印PDF的参数，仅在format为pdf时生效
+type PDFOptions struct {
+	PaperSize       string   `json:"paperSize,omitempty"`       // A3、A4、A5、Letter、Legal、Tabloid，默认A4
+	Landscape       bool     `json:"landscape,omitempty"`       // 横向打印
+	Margin          *float64 `json:"margin,omitempty"`          // 四边页边距(毫米)，默认10
+	PrintBackground *bool    `json:"printBackground,omitempty"` // 打印背景颜色和图片，默认打印，与截图的外观一致
+	HeaderTemplate  string   `json:"headerTemplate,omitempty"`  // 页眉HTML，可使用date、title、url、pageNumber、totalPages等class
+	FooterTemplate  string   `json:"footerTemplate,omitempty"`  // 页脚HTML，格式同页眉
+}
+
+// paperSizeNames 返回所有纸张名称，用于提示信息
+func paperSizeNames() string {
+	names := make([]string, 0, len(paperSizes))
+	for name := range paperSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// validate 检查纸张和页边距
+func (p *PDFOptions) validate() error {
+	if p == nil {
+		return nil
+	}
+	if _, ok := paperSizes[strings.ToUpper(p.PaperSize)]; !ok && p.PaperSize != "" {
+		return fmt.Errorf("不支持的纸张尺寸 %q，可用: %s", p.PaperSize, paperSizeNames())
+	}
+	if p.Margin != nil && (*p.Margin < 0 || *p.Margin > 100) {
+		return fmt.Errorf("页边距 %gmm 超出范围(0-100)", *p.Margin)
+	}
+	return nil
+}
+
+// printPDF 将当前页面打印为PDF
+func printPDF(res *[]byte, p *PDFOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if p == nil {
+			p = &PDFOptions{}
+		}
+		paper := defaultPaperSize
+		if p.PaperSize != "" {
+			paper = strings.ToUpper(p.PaperSize)
+		}
+		size := paperSizes[paper]
+		margin := defaultPDFMargin
+		if p.Margin != nil {
+			margin = *p.Margin
+		}
+		margin /= 25.4 // 毫米转英寸
+
+		params := page.PrintToPDF().
+			WithPaperWidth(size[0]).
+			WithPaperHeight(size[1]).
+			WithLandscape(p.Landscape).
+			WithPrintBackground(p.PrintBackground == nil || *p.PrintBackground).
+			WithMarginTop(margin).
+			WithMarginBottom(margin).
+			WithMarginLeft(margin).
+			WithMarginRight(margin)
+		if p.HeaderTemplate != "" || p.FooterTemplate != "" {
+			// 只设置其中一个时，另一个使用空模板，避免Chrome填入默认的标题和URL
+			header, footer := p.HeaderTemplate, p.FooterTemplate
+			if header == "" {
+				header = "<span></span>"
+			}
+			if footer == "" {
+				footer = "<span></span>"
+			}
+			params = params.WithDisplayHeaderFooter(true).WithHeaderTemplate(header).WithFooterTemplate(footer)
+		}
+
+		var err error
+		*res, _, err = params.Do(ctx)
+		return err
+	})
+}
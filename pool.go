@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// 浏览器池默认参数
+const (
+	defaultPoolBrowsers     = 2
+	defaultPoolTabs         = 3
+	defaultPoolRecycleAfter = 100
+	poolHealthCheckInterval = 30 * time.Second
+	poolHealthCheckTimeout  = 10 * time.Second
+)
+
+var errPoolClosed = errors.New("浏览器池已关闭")
+
+// PoolConfig 浏览器池配置
+type PoolConfig struct {
+	Browsers       int // 浏览器进程数
+	TabsPerBrowser int // 每个浏览器同时打开的标签页数
+	RecycleAfter   int // 每个浏览器处理多少个页面后重启，0表示不回收
+	// 持久化的用户数据目录，设置后标签页共用浏览器的默认上下文以保留登录状态；
+	// 同一目录只能由一个Chrome进程使用，此时Browsers固定为1
+	UserDataDir string
+	// 启动参数中的代理，只用于使用持久化用户数据目录的池；其他池按标签页在浏览器上下文中设置代理
+	Proxy *proxyServer
+}
+
+// browserInstance 池中的单个无头Chrome进程
+type browserInstance struct {
+	slot        int
+	allocCancel context.CancelFunc
+	ctx         context.Context // 浏览器根上下文，进程退出时会被取消
+	cancel      context.CancelFunc
+	ready       chan struct{} // 浏览器启动完成后关闭
+	err         error         // 启动失败原因，ready关闭后可读
+	active      int           // 正在使用的标签页数
+	served      int           // 已处理的页面数
+	retired     bool          // 已从槽位中移除，等待标签页全部释放后关闭
+	closed      chan struct{} // 进程退出、临时目录释放后关闭
+	closeOnce   sync.Once
+}
+
+// alive 判断浏览器进程是否仍然可用
+func (b *browserInstance) alive() bool {
+	select {
+	case <-b.ready:
+		return b.err == nil && b.ctx.Err() == nil
+	default:
+		return true // 仍在启动中
+	}
+}
+
+// close 关闭浏览器进程并释放临时目录，返回时进程已退出
+func (b *browserInstance) close() {
+	b.closeOnce.Do(func() {
+		b.cancel()
+		b.allocCancel()
+		close(b.closed)
+	})
+}
+
+// BrowserPool 管理一组长期运行的无头Chrome进程，按标签页出租给截图任务
+type BrowserPool struct {
+	cfg       PoolConfig
+	mu        sync.Mutex
+	browsers  []*browserInstance // 按槽位存放，nil表示尚未启动或已被回收
+	dirOwner  *browserInstance   // 最近一个使用UserDataDir的浏览器，新进程需等它退出后才能启动
+	slots     chan struct{}      // 全局标签页配额
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBrowserPool 创建浏览器池，浏览器进程在首次使用时才启动
+func NewBrowserPool(cfg PoolConfig) *BrowserPool {
+	if cfg.Browsers <= 0 {
+		cfg.Browsers = defaultPoolBrowsers
+	}
+	if cfg.TabsPerBrowser <= 0 {
+		cfg.TabsPerBrowser = defaultPoolTabs
+	}
+	if cfg.RecycleAfter < 0 {
+		cfg.RecycleAfter = 0
+	}
+	if cfg.UserDataDir != "" {
+		cfg.Browsers = 1
+	}
+
+	p := &BrowserPool{
+		cfg:      cfg,
+		browsers: make([]*browserInstance, cfg.Browsers),
+		slots:    make(chan struct{}, cfg.Browsers*cfg.TabsPerBrowser),
+		done:     make(chan struct{}),
+	}
+	go p.healthLoop()
+	return p
+}
+
+// Capacity 返回池可同时提供的标签页总数
+func (p *BrowserPool) Capacity() int {
+	return cap(p.slots)
+}
+
+// chromeOptions 构造无头Chrome启动参数
+func chromeOptions() []chromedp.ExecAllocatorOption {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+		// 禁用不必要的功能以提高性能
+		chromedp.Flag("disable-extensions", true),
+		chromedp.Flag("disable-plugins", true),
+		chromedp.Flag("disable-features", "TranslateUI,BlinkGenPropertyTrees"),
+		chromedp.WindowSize(defaultViewportWidth, defaultViewportHeight), // 固定窗口大小提高性能
+	)
+	if appCAs != nil {
+		// 服务器发送的证书链中包含这些CA时，Chrome接受该证书；需要与user-data-dir一起使用，chromedp默认会设置
+		opts = append(opts, chromedp.Flag("ignore-certificate-errors-spki-list", strings.Join(appCAs.spki, ",")))
+	}
+	return opts
+}
+
+// launch 在指定槽位启动一个新的浏览器进程，调用方需持有p.mu
+func (p *BrowserPool) launch(slot int) *browserInstance {
+	opts := chromeOptions()
+	if p.cfg.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(p.cfg.UserDataDir))
+	}
+	if p.cfg.Proxy != nil {
+		opts = append(opts, chromedp.ProxyServer(p.cfg.Proxy.String()))
+		if bypass := p.cfg.Proxy.bypassList(); bypass != "" {
+			opts = append(opts, chromedp.Flag("proxy-bypass-list", bypass))
+		}
+	}
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	b := &browserInstance{
+		slot:        slot,
+		allocCancel: allocCancel,
+		ctx:         ctx,
+		cancel:      cancel,
+		ready:       make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	prev := p.dirOwner
+	if p.cfg.UserDataDir != "" {
+		p.dirOwner = b
+	}
+	go func() {
+		if prev != nil {
+			// 旧进程退出前仍锁定着用户数据目录，此时启动的Chrome会直接失败；
+			// 旧进程在其标签页全部释放后关闭
+			select {
+			case <-prev.closed:
+			case <-ctx.Done():
+			}
+		}
+		// 首次Run会启动浏览器进程并连接到初始标签页
+		if err := chromedp.Run(ctx); err != nil {
+			b.err = fmt.Errorf("启动浏览器失败: %v", err)
+			b.close()
+		}
+		close(b.ready)
+	}()
+	p.browsers[slot] = b
+	return b
+}
+
+// pick 选择一个空闲标签页最多的浏览器，必要时启动或替换崩溃的进程
+func (p *BrowserPool) pick() *browserInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *browserInstance
+	for slot, b := range p.browsers {
+		if b != nil && !b.alive() {
+			// 浏览器已崩溃，移出槽位，等待标签页释放后清理
+			b.retired = true
+			if b.active == 0 {
+				go b.close()
+			}
+			b = nil
+		}
+		if b == nil {
+			b = p.launch(slot)
+		}
+		if b.active < p.cfg.TabsPerBrowser && (best == nil || b.active < best.active) {
+			best = b
+		}
+	}
+	if best != nil {
+		best.active++
+	}
+	return best
+}
+
+// Acquire 从池中租用一个标签页，调用方使用完毕后必须调用Release。
+// proxy不为空时标签页所在的浏览器上下文通过该代理访问网络
+func (p *BrowserPool) Acquire(ctx context.Context, proxy *proxyServer) (*PageLease, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, errPoolClosed
+	}
+
+	b := p.pick()
+	if b == nil {
+		// 配额与实际占用不一致时不应发生
+		<-p.slots
+		return nil, errors.New("浏览器池没有可用的标签页")
+	}
+
+	select {
+	case <-b.ready:
+	case <-ctx.Done():
+		p.release(b)
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		p.release(b)
+		return nil, b.err
+	}
+
+	// 每个标签页使用独立的浏览器上下文，避免Cookie和缓存互相影响；
+	// 使用持久化用户数据目录时共用默认上下文，Cookie写入用户数据目录
+	var tabOpts []chromedp.ContextOption
+	if p.cfg.UserDataDir == "" {
+		tabOpts = append(tabOpts, chromedp.WithNewBrowserContext(func(params *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			if proxy == nil {
+				return params
+			}
+			return params.WithProxyServer(proxy.String()).WithProxyBypassList(proxy.bypassList())
+		}))
+	} else {
+		proxy = p.cfg.Proxy
+	}
+	tabCtx, tabCancel := chromedp.NewContext(b.ctx, tabOpts...)
+	// 在租约自身的上下文中创建标签页，chromedp的事件循环绑定在首次Run的ctx上，
+	// 若由截图时带超时的ctx创建，一次截图结束后标签页就无法再响应命令
+	if err := chromedp.Run(tabCtx); err != nil {
+		tabCancel()
+		p.release(b)
+		return nil, fmt.Errorf("创建标签页失败: %v", err)
+	}
+	return &PageLease{pool: p, browser: b, ctx: tabCtx, cancel: tabCancel, proxy: proxy}, nil
+}
+
+// release 归还标签页配额，并按需回收浏览器进程
+func (p *BrowserPool) release(b *browserInstance) {
+	p.mu.Lock()
+	b.active--
+	b.served++
+	if !b.retired && (!b.alive() || (p.cfg.RecycleAfter > 0 && b.served >= p.cfg.RecycleAfter)) {
+		b.retired = true
+		if p.browsers[b.slot] == b {
+			p.browsers[b.slot] = nil
+		}
+	}
+	closeNow := b.retired && b.active == 0
+	p.mu.Unlock()
+
+	if closeNow {
+		go b.close()
+	}
+	<-p.slots
+}
+
+// healthLoop 定期检查空闲浏览器是否仍能响应，无响应的进程将被关闭
+func (p *BrowserPool) healthLoop() {
+	ticker := time.NewTicker(poolHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *BrowserPool) checkHealth() {
+	p.mu.Lock()
+	var idle []*browserInstance
+	for _, b := range p.browsers {
+		if b != nil && b.active == 0 {
+			select {
+			case <-b.ready:
+				idle = append(idle, b)
+			default:
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	for _, b := range idle {
+		ctx, cancel := context.WithTimeout(b.ctx, poolHealthCheckTimeout)
+		_, err := chromedp.Targets(ctx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		fmt.Printf("浏览器 #%d 健康检查失败，正在回收: %v\n", b.slot, err)
+		p.mu.Lock()
+		if !b.retired {
+			b.retired = true
+			if p.browsers[b.slot] == b {
+				p.browsers[b.slot] = nil
+			}
+		}
+		closeNow := b.active == 0
+		p.mu.Unlock()
+		if closeNow {
+			b.close()
+		}
+	}
+}
+
+// Close 关闭池中所有浏览器进程
+func (p *BrowserPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.mu.Lock()
+		browsers := p.browsers
+		p.browsers = make([]*browserInstance, len(browsers))
+		p.mu.Unlock()
+		for _, b := range browsers {
+			if b != nil {
+				b.close()
+			}
+		}
+	})
+}
+
+// PageLease 租用的标签页
+type PageLease struct {
+	pool     *BrowserPool
+	browser  *browserInstance
+	ctx      context.Context
+	cancel   context.CancelFunc
+	proxy    *proxyServer // 标签页使用的代理，直连时为nil
+	released sync.Once
+}
+
+// Context 返回标签页的chromedp上下文
+func (l *PageLease) Context() context.Context {
+	return l.ctx
+}
+
+// Proxy 返回标签页使用的代理，直连时为nil
+func (l *PageLease) Proxy() *proxyServer {
+	return l.proxy
+}
+
+// BrowserLost 判断标签页所属的浏览器进程是否已退出
+func (l *PageLease) BrowserLost() bool {
+	return l.browser.ctx.Err() != nil
+}
+
+// Release 关闭标签页并归还给池
+func (l *PageLease) Release() {
+	l.released.Do(func() {
+		l.cancel()
+		l.pool.release(l.browser)
+	})
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// 登录宏的默认超时时间(秒)
+const defaultLoginTimeout = 60
+
+// 检查登录状态是否仍然有效的间隔和超时时间
+const (
+	loginCheckInterval = 10 * time.Minute
+	loginCheckTimeout  = 15 * time.Second
+)
+
+// LoginMacro 登录宏：打开登录页，填写账号密码并提交，等待表示登录成功的元素出现。
+// 账号和密码从环境变量读取，不写入配置文件
+type LoginMacro struct {
+	URL              string `json:"url"`
+	UsernameSelector string `json:"usernameSelector"`
+	PasswordSelector string `json:"passwordSelector"`
+	SubmitSelector   string `json:"submitSelector,omitempty"` // 为空时在密码框中按回车提交
+	UsernameEnv      string `json:"usernameEnv"`
+	PasswordEnv      string `json:"passwordEnv"`
+	SuccessSelector  string `json:"successSelector"`   // 登录成功后出现的元素；已登录时打开url也应显示该元素，用于检查登录是否过期
+	Steps            []Step `json:"steps,omitempty"`   // 填写表单前执行的步骤，如关闭Cookie提示、切换到账号密码登录
+	Timeout          int    `json:"timeout,omitempty"` // 超时时间(秒)，默认60
+}
+
+// validate 检查登录宏的必填项
+func (m *LoginMacro) validate() error {
+	if m == nil {
+		return nil
+	}
+	switch {
+	case m.URL == "":
+		return errors.New("登录宏缺少url")
+	case m.UsernameSelector == "" || m.PasswordSelector == "":
+		return errors.New("登录宏缺少账号或密码输入框的选择器")
+	case m.UsernameEnv == "" || m.PasswordEnv == "":
+		return errors.New("登录宏缺少保存账号和密码的环境变量名")
+	case m.SuccessSelector == "":
+		return errors.New("登录宏缺少successSelector")
+	}
+	return validateSteps(m.Steps)
+}
+
+// ProfileConfig 命名的浏览器配置，截图在WebCut管理的持久化用户数据目录中进行，Cookie和本地存储跨任务保留
+type ProfileConfig struct {
+	Login *LoginMacro `json:"login,omitempty"` // 首次使用前执行一次的登录宏，为空时不自动登录
+}
+
+// profileLogin 登录状态文件的内容
+type profileLogin struct {
+	LoggedInAt time.Time `json:"loggedInAt"`
+}
+
+// browserProfile 运行中的浏览器配置，每个配置对应一个使用其用户数据目录的浏览器池
+type browserProfile struct {
+	name      string
+	cfg       ProfileConfig
+	dir       string
+	pool      *BrowserPool
+	mu        sync.Mutex // 串行化登录，并发的截图只会触发一次登录
+	loggedIn  *time.Time
+	checkedAt time.Time // 上次确认登录状态有效的时间
+}
+
+// loginStatePath 返回记录登录时间的文件路径，文件存在表示登录宏已成功执行
+func loginStatePath(dir string) string {
+	return filepath.Join(dir, "login.json")
+}
+
+// readLoginState 返回上次登录成功的时间，尚未登录时返回nil
+func readLoginState(dir string) *time.Time {
+	data, err := os.ReadFile(loginStatePath(dir))
+	if err != nil {
+		return nil
+	}
+	var state profileLogin
+	if json.Unmarshal(data, &state) != nil {
+		return nil
+	}
+	return &state.LoggedInAt
+}
+
+// ensureLogin 在配置尚未登录、登录已过期或force为true时执行登录宏。
+// 已登录时每隔loginCheckInterval打开登录页检查一次successSelector，服务器端会话过期后重新登录
+func (p *browserProfile) ensureLogin(ctx context.Context, force bool) error {
+	if p.cfg.Login == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !force && p.loggedIn != nil && time.Since(p.checkedAt) < loginCheckInterval {
+		return nil
+	}
+	if !force && p.loggedIn == nil {
+		// 上次运行时已登录，用户数据目录中可能保留着登录状态
+		p.loggedIn = readLoginState(p.dir)
+	}
+	if !force && p.loggedIn != nil {
+		if p.checkLogin(ctx) {
+			p.checkedAt = time.Now()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("浏览器配置 %s 的登录状态已失效，重新登录\n", p.name)
+	}
+
+	p.loggedIn = nil
+	os.Remove(loginStatePath(p.dir))
+	if err := p.login(ctx); err != nil {
+		return fmt.Errorf("浏览器配置 %s 登录失败: %v", p.name, err)
+	}
+	now := time.Now()
+	data, _ := json.Marshal(profileLogin{LoggedInAt: now})
+	if err := os.WriteFile(loginStatePath(p.dir), data, 0600); err != nil {
+		return fmt.Errorf("保存登录状态失败: %v", err)
+	}
+	p.loggedIn = &now
+	p.checkedAt = now
+	fmt.Printf("浏览器配置 %s 登录成功\n", p.name)
+	return nil
+}
+
+// checkLogin 打开登录页并等待successSelector出现，出现表示登录状态仍然有效
+func (p *browserProfile) checkLogin(parent context.Context) bool {
+	m := p.cfg.Login
+	lease, err := p.pool.Acquire(parent, nil)
+	if err != nil {
+		return false
+	}
+	defer lease.Release()
+	ctx, cancel := context.WithTimeout(lease.Context(), loginCheckTimeout)
+	defer cancel()
+	stop := context.AfterFunc(parent, cancel)
+	defer stop()
+	return chromedp.Run(ctx,
+		applyTLSMode(CaptureOptions{}.tlsMode()),
+		chromedp.Navigate(m.URL),
+		chromedp.WaitVisible(m.SuccessSelector, chromedp.ByQuery),
+	) == nil
+}
+
+// login 在配置的浏览器中执行登录宏
+func (p *browserProfile) login(parent context.Context) error {
+	m := p.cfg.Login
+	username, password := os.Getenv(m.UsernameEnv), os.Getenv(m.PasswordEnv)
+	if username == "" || password == "" {
+		return fmt.Errorf("环境变量 %s 或 %s 未设置", m.UsernameEnv, m.PasswordEnv)
+	}
+
+	lease, err := p.pool.Acquire(parent, nil)
+	if err != nil {
+		return fmt.Errorf("获取浏览器标签页失败: %v", err)
+	}
+	defer lease.Release()
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = defaultLoginTimeout
+	}
+	ctx, cancel := context.WithTimeout(lease.Context(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	stop := context.AfterFunc(parent, cancel)
+	defer stop()
+
+	if err := chromedp.Run(ctx, applyTLSMode(CaptureOptions{}.tlsMode()), chromedp.Navigate(m.URL)); err != nil {
+		return fmt.Errorf("打开登录页失败: %v", err)
+	}
+	if err := runSteps(ctx, m.Steps); err != nil {
+		return err
+	}
+	if err := chromedp.Run(ctx,
+		chromedp.WaitVisible(m.UsernameSelector, chromedp.ByQuery),
+		chromedp.SetValue(m.UsernameSelector, "", chromedp.ByQuery),
+		chromedp.SendKeys(m.UsernameSelector, username, chromedp.ByQuery),
+		chromedp.SetValue(m.PasswordSelector, "", chromedp.ByQuery),
+		chromedp.SendKeys(m.PasswordSelector, password, chromedp.ByQuery),
+	); err != nil {
+		return fmt.Errorf("填写登录表单失败: %v", err)
+	}
+	submit := chromedp.SendKeys(m.PasswordSelector, kb.Enter, chromedp.ByQuery)
+	if m.SubmitSelector != "" {
+		submit = chromedp.Click(m.SubmitSelector, chromedp.ByQuery)
+	}
+	if err := chromedp.Run(ctx, submit); err != nil {
+		return fmt.Errorf("提交登录表单失败: %v", err)
+	}
+	if err := chromedp.Run(ctx, chromedp.WaitVisible(m.SuccessSelector, chromedp.ByQuery)); err != nil {
+		return fmt.Errorf("等待登录成功的标志 %s 失败: %v", m.SuccessSelector, err)
+	}
+	return nil
+}
+
+// profileManager 按需启动各个浏览器配置的浏览器池
+type profileManager struct {
+	mu       sync.Mutex
+	profiles map[string]*browserProfile
+}
+
+var profiles = &profileManager{profiles: make(map[string]*browserProfile)}
+
+// get 返回已启动的浏览器配置，首次使用时创建用户数据目录和浏览器池
+func (m *profileManager) get(name string) (*browserProfile, error) {
+	cfg, ok := appConfig.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("浏览器配置 %q 不存在", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.profiles[name]; ok {
+		return p, nil
+	}
+	dir := filepath.Join(appConfig.ProfileDir, name)
+	userDataDir := filepath.Join(dir, "chrome")
+	if err := os.MkdirAll(userDataDir, 0700); err != nil {
+		return nil, fmt.Errorf("无法创建浏览器配置目录: %v", err)
+	}
+	// 同一用户数据目录不能由两个进程同时使用，因此不按页面数回收浏览器
+	// 浏览器配置的标签页共用默认上下文，只能在启动参数中使用配置文件中的默认代理
+	proxy, err := appConfig.Proxy.pick(0)
+	if err != nil {
+		return nil, err
+	}
+	poolCfg := PoolConfig{UserDataDir: userDataDir, Proxy: proxy}
+	if browserPool != nil {
+		poolCfg.TabsPerBrowser = browserPool.cfg.TabsPerBrowser
+	}
+	p := &browserProfile{name: name, cfg: cfg, dir: dir, pool: NewBrowserPool(poolCfg)}
+	m.profiles[name] = p
+	return p, nil
+}
+
+// closeAll 关闭所有浏览器配置的浏览器池
+func (m *profileManager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, p := range m.profiles {
+		p.pool.Close()
+		delete(m.profiles, name)
+	}
+}
+
+// acquireLease 按截图参数租用标签页：指定了浏览器配置时先确保已登录，再从该配置的浏览器池租用，
+// 否则从全局池租用一个使用临时浏览器上下文的标签页，并按代理设置选择代理
+func acquireLease(ctx context.Context, opts CaptureOptions) (*PageLease, error) {
+	pool := browserPool
+	proxy, err := opts.proxy().pick(-1)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Profile != "" {
+		p, err := profiles.get(opts.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.ensureLogin(ctx, false); err != nil {
+			return nil, err
+		}
+		pool, proxy = p.pool, nil
+	}
+	lease, err := pool.Acquire(ctx, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("获取浏览器标签页失败: %v", err)
+	}
+	return lease, nil
+}
+
+// profileNames 返回配置文件中的浏览器配置名称
+func profileNames() []string {
+	names := make([]string, 0, len(appConfig.Profiles))
+	for name := range appConfig.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerProfileHandlers 注册浏览器配置的HTTP接口
+func registerProfileHandlers() {
+	// 列出浏览器配置及其登录状态
+	http.HandleFunc("GET /api/profiles", func(w http.ResponseWriter, r *http.Request) {
+		list := make([]map[string]interface{}, 0, len(appConfig.Profiles))
+		for _, name := range profileNames() {
+			item := map[string]interface{}{"name": name, "login": appConfig.Profiles[name].Login != nil}
+			if t := readLoginState(filepath.Join(appConfig.ProfileDir, name)); t != nil {
+				item["loggedInAt"] = *t
+			}
+			list = append(list, item)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"profiles": list})
+	})
+
+	// 重新执行登录宏，用于登录状态过期后刷新
+	http.HandleFunc("POST /api/profiles/{name}/login", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		p, err := profiles.get(name)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if p.cfg.Login == nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("浏览器配置 %s 没有登录宏", name))
+			return
+		}
+		if err := p.ensureLogin(r.Context(), true); err != nil {
+			writeJSONError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "loggedInAt": readLoginState(p.dir)})
+	})
+}
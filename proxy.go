@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// ProxyOptions 代理设置，每个标签页使用独立的浏览器上下文，因此同时进行的截图可以使用不同的代理
+type ProxyOptions struct {
+	// 代理地址列表，如http://127.0.0.1:8080、socks5://10.0.0.2:1080，HTTP代理可包含user:pass@；
+	// 为空表示直连，可用于覆盖配置文件中的默认代理
+	Servers []string `json:"servers"`
+	Rotate  bool     `json:"rotate,omitempty"` // 依次轮流使用列表中的代理，否则只使用第一个
+	Bypass  []string `json:"bypass,omitempty"` // 不经过代理的主机，如localhost、*.internal
+}
+
+// 支持的代理协议
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks4": true, "socks5": true}
+
+// proxyServer 解析后的单个代理
+type proxyServer struct {
+	url    *url.URL // 包含认证信息，用于Go发出的请求
+	bypass []string
+}
+
+// String 返回不含认证信息的代理地址，用于Chrome参数和截图记录
+func (s *proxyServer) String() string {
+	return s.url.Scheme + "://" + s.url.Host
+}
+
+// bypassList 返回Chrome格式的绕过列表
+func (s *proxyServer) bypassList() string {
+	return strings.Join(s.bypass, ";")
+}
+
+// credentials 返回代理的账号和密码，没有代理或代理不需要认证时ok为false
+func (s *proxyServer) credentials() (username, password string, ok bool) {
+	if s == nil || s.url.User == nil {
+		return "", "", false
+	}
+	password, _ = s.url.User.Password()
+	return s.url.User.Username(), password, true
+}
+
+// httpProxy 返回Go HTTP客户端使用的代理函数，跳过绕过列表中的主机
+func (s *proxyServer) httpProxy() func(*http.Request) (*url.URL, error) {
+	return func(r *http.Request) (*url.URL, error) {
+		for _, b := range s.bypass {
+			if matchHost(b, r.URL.Hostname()) {
+				return nil, nil
+			}
+		}
+		return s.url, nil
+	}
+}
+
+// parseProxy 解析代理地址，省略协议时视为HTTP代理
+func parseProxy(server string, bypass []string) (*proxyServer, error) {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("无效的代理地址 %q", server)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !proxySchemes[u.Scheme] {
+		return nil, fmt.Errorf("不支持的代理协议 %q，可用: http, https, socks4, socks5", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("代理地址 %q 缺少主机或端口", server)
+	}
+	if u.User != nil && strings.HasPrefix(u.Scheme, "socks") {
+		return nil, errors.New("Chrome不支持需要认证的SOCKS代理")
+	}
+	return &proxyServer{url: u, bypass: bypass}, nil
+}
+
+// validate 检查代理列表
+func (p *ProxyOptions) validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, s := range p.Servers {
+		if _, err := parseProxy(s, p.Bypass); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// proxyRotation 没有序号的截图轮流使用代理时的全局计数
+var proxyRotation atomic.Uint64
+
+// pick 选择本次截图使用的代理：轮询时第index个URL使用第index个代理(循环)，
+// index为负数时按全局计数轮流选择；没有代理时返回nil
+func (p *ProxyOptions) pick(index int) (*proxyServer, error) {
+	if p == nil || len(p.Servers) == 0 {
+		return nil, nil
+	}
+	i := 0
+	if p.Rotate {
+		if index < 0 {
+			index = int(proxyRotation.Add(1) - 1)
+		}
+		i = index % len(p.Servers)
+	}
+	return parseProxy(p.Servers[i], p.Bypass)
+}
+
+// at 返回只包含第index个URL所用代理的副本，批量任务在派发时调用，使结果能记录实际使用的代理
+func (p *ProxyOptions) at(index int) *ProxyOptions {
+	if p == nil || !p.Rotate || len(p.Servers) == 0 {
+		return p
+	}
+	return &ProxyOptions{Servers: []string{p.Servers[index%len(p.Servers)]}, Bypass: p.Bypass}
+}
+
+// proxy 返回截图使用的代理设置，请求中未设置时使用配置文件中的默认代理
+func (o CaptureOptions) proxy() *ProxyOptions {
+	if o.Proxy != nil {
+		return o.Proxy
+	}
+	return appConfig.Proxy
+}
+
+// proxyLabel 返回未轮询时本次截图使用的代理地址，直连时为空；
+// 浏览器配置的浏览器总是使用配置文件中的第一个代理
+func (o CaptureOptions) proxyLabel() string {
+	p := o.proxy()
+	if o.Profile != "" {
+		p = appConfig.Proxy.at(0)
+	}
+	if p == nil || len(p.Servers) == 0 || p.Rotate && len(p.Servers) > 1 {
+		return ""
+	}
+	s, err := parseProxy(p.Servers[0], p.Bypass)
+	if err != nil {
+		return ""
+	}
+	return s.String()
+}
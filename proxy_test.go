@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProxyOptionsAt(t *testing.T) {
+	rotating := &ProxyOptions{Servers: []string{"a:1", "b:2", "c:3"}, Rotate: true, Bypass: []string{"localhost"}}
+	fixed := &ProxyOptions{Servers: []string{"a:1", "b:2"}}
+	tests := []struct {
+		name  string
+		p     *ProxyOptions
+		index int
+		want  *ProxyOptions
+	}{
+		{name: "nil", p: nil, index: 3, want: nil},
+		{name: "not rotating", p: fixed, index: 1, want: fixed},
+		{name: "empty", p: &ProxyOptions{Rotate: true}, index: 0, want: &ProxyOptions{Rotate: true}},
+		{name: "first", p: rotating, index: 0, want: &ProxyOptions{Servers: []string{"a:1"}, Bypass: []string{"localhost"}}},
+		{name: "third", p: rotating, index: 2, want: &ProxyOptions{Servers: []string{"c:3"}, Bypass: []string{"localhost"}}},
+		{name: "wraps around", p: rotating, index: 4, want: &ProxyOptions{Servers: []string{"b:2"}, Bypass: []string{"localhost"}}},
+	}
+	for _, tt := range tests {
+		if got := tt.p.at(tt.index); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: at(%d) = %+v, want %+v", tt.name, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestProxyOptionsPick(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       *ProxyOptions
+		index   int
+		want    string // 为空表示直连
+		wantErr bool
+	}{
+		{name: "nil", p: nil, index: 0},
+		{name: "no servers", p: &ProxyOptions{}, index: 0},
+		{name: "default scheme", p: &ProxyOptions{Servers: []string{"127.0.0.1:8080"}}, index: 0, want: "http://127.0.0.1:8080"},
+		{name: "not rotating uses first", p: &ProxyOptions{Servers: []string{"a:1", "socks5://b:2"}}, index: 1, want: "http://a:1"},
+		{name: "rotating", p: &ProxyOptions{Servers: []string{"a:1", "socks5://b:2"}, Rotate: true}, index: 1, want: "socks5://b:2"},
+		{name: "rotating wraps", p: &ProxyOptions{Servers: []string{"a:1", "socks5://b:2"}, Rotate: true}, index: 2, want: "http://a:1"},
+		{name: "credentials hidden", p: &ProxyOptions{Servers: []string{"http://user:pass@a:1"}}, index: 0, want: "http://a:1"},
+		{name: "unsupported scheme", p: &ProxyOptions{Servers: []string{"ftp://a:1"}}, index: 0, wantErr: true},
+		{name: "missing port", p: &ProxyOptions{Servers: []string{"http://a"}}, index: 0, wantErr: true},
+		{name: "socks with credentials", p: &ProxyOptions{Servers: []string{"socks5://u:p@a:1"}}, index: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.pick(tt.index)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: pick(%d) error = %v, wantErr %v", tt.name, tt.index, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		label := ""
+		if got != nil {
+			label = got.String()
+		}
+		if label != tt.want {
+			t.Errorf("%s: pick(%d) = %q, want %q", tt.name, tt.index, label, tt.want)
+		}
+	}
+}
+
+func TestProxyOptionsPickRotatesWithoutIndex(t *testing.T) {
+	p := &ProxyOptions{Servers: []string{"a:1", "b:2"}, Rotate: true}
+	first, err := p.pick(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.pick(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.String() == second.String() {
+		t.Errorf("pick(-1) returned %s twice, want the next proxy", first)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// reportItem 报告中单个URL的展示数据
+type reportItem struct {
+	Index       int
+	URL         string
+	FinalURL    string
+	Title       string
+	HTTPStatus  int
+	Status      string
+	Error       string
+	Proxy       string
+	Certificate *CertificateInfo
+	Images      []template.URL // 内嵌的data URI或导出目录中的相对路径，多个元素或分段长图时有多张
+	IsPDF       bool           // 结果为PDF文档，以链接代替缩略图
+	CapturedAt  string
+}
+
+// reportData 报告模板数据
+type reportData struct {
+	JobID        string
+	GeneratedAt  string
+	Total        int
+	SuccessCount int
+	FailureCount int
+	Items        []reportItem
+}
+
+// writeJobReport 根据任务结果生成独立的HTML报告。
+// names为nil时图片以data URI内嵌，否则按names中的文件名引用导出目录中的图片
+func writeJobReport(w io.Writer, j *Job, names map[int][]string) error {
+	s := j.Snapshot(true)
+	data := reportData{
+		JobID:        s.ID,
+		GeneratedAt:  time.Now().Format("2006-01-02 15:04:05"),
+		Total:        s.Total,
+		SuccessCount: s.SuccessCount,
+		FailureCount: s.FailureCount,
+	}
+	for _, r := range s.Results {
+		item := reportItem{
+			Index:       r.Index + 1,
+			URL:         r.URL,
+			FinalURL:    r.FinalURL,
+			Title:       r.Title,
+			HTTPStatus:  r.HTTPStatus,
+			Proxy:       r.Proxy,
+			Certificate: r.Certificate,
+			Status:      r.Status,
+			Error:       r.Error,
+		}
+		if r.CapturedAt != nil {
+			item.CapturedAt = r.CapturedAt.Format("2006-01-02 15:04:05")
+		}
+		if names == nil {
+			for _, img := range j.Images(r.Index) {
+				mime := http.DetectContentType(img)
+				item.Images = append(item.Images, template.URL("data:"+mime+";base64,"+base64.StdEncoding.EncodeToString(img)))
+				item.IsPDF = mime == mimePDF
+			}
+		} else {
+			for _, name := range names[r.Index] {
+				item.Images = append(item.Images, template.URL(name))
+				item.IsPDF = strings.HasSuffix(name, ".pdf")
+			}
+		}
+		data.Items = append(data.Items, item)
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>WebCut截图报告 - {{.JobID}}</title>
+	<style>
+		body {
+			font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
+			margin: 0 auto;
+			padding: 20px;
+			max-width: 1400px;
+			background-color: #f5f5f5;
+			color: #333;
+		}
+		h1 {
+			margin-bottom: 5px;
+		}
+		.summary {
+			color: #666;
+			margin-bottom: 20px;
+		}
+		.filters button {
+			background-color: #fff;
+			border: 1px solid #ddd;
+			border-radius: 4px;
+			padding: 6px 14px;
+			margin-right: 6px;
+			cursor: pointer;
+		}
+		.filters button.active {
+			background-color: #4CAF50;
+			border-color: #4CAF50;
+			color: #fff;
+		}
+		.grid {
+			display: grid;
+			grid-template-columns: repeat(auto-fill, minmax(300px, 1fr));
+			gap: 15px;
+			margin-top: 20px;
+		}
+		.card {
+			background-color: #fff;
+			border: 1px solid #eee;
+			border-radius: 4px;
+			padding: 10px;
+		}
+		.card.failed, .card.cancelled {
+			border-color: #f5c6cb;
+			background-color: #f8d7da;
+		}
+		.card img {
+			width: 100%;
+			height: 200px;
+			object-fit: cover;
+			object-position: top;
+			border-radius: 4px;
+			cursor: zoom-in;
+		}
+		.card img + img {
+			margin-top: 6px;
+		}
+		.pdf {
+			display: flex;
+			align-items: center;
+			justify-content: center;
+			height: 200px;
+			border-radius: 4px;
+			background-color: #f0f0f0;
+			color: #c62828;
+			font-size: 18px;
+			text-decoration: none;
+		}
+		.title {
+			font-weight: 500;
+			margin-top: 8px;
+		}
+		.url, .meta {
+			font-size: 13px;
+			color: #555;
+			word-break: break-all;
+			margin-top: 4px;
+		}
+		.error {
+			color: #721c24;
+			font-size: 13px;
+			margin-top: 4px;
+			word-break: break-word;
+		}
+		.overlay {
+			display: none;
+			position: fixed;
+			top: 0;
+			left: 0;
+			right: 0;
+			bottom: 0;
+			background-color: rgba(0,0,0,0.85);
+			overflow: auto;
+			cursor: zoom-out;
+			text-align: center;
+		}
+		.overlay img {
+			max-width: 95%;
+			margin: 20px auto;
+		}
+	</style>
+</head>
+<body>
+	<h1>WebCut截图报告</h1>
+	<div class="summary">任务 {{.JobID}} · 生成于 {{.GeneratedAt}} · 共 {{.Total}} 个，成功 {{.SuccessCount}} 个，失败 {{.FailureCount}} 个</div>
+	<div class="filters">
+		<button data-filter="all" class="active">全部</button>
+		<button data-filter="success">成功</button>
+		<button data-filter="failed">失败</button>
+	</div>
+	<div class="grid">
+		{{range .Items}}
+		<div class="card {{.Status}}" data-status="{{.Status}}">
+			{{$item := .}}
+			{{if .IsPDF}}<a class="pdf" href="{{index .Images 0}}" download="webcut_{{.Index}}.pdf">PDF文档</a>{{else}}{{range .Images}}<img src="{{.}}" alt="{{$item.URL}}" loading="lazy">{{end}}{{end}}
+			{{if gt (len .Images) 1}}<div class="meta">共 {{len .Images}} 张图片</div>{{end}}
+			<div class="title">#{{.Index}} {{if .Title}}{{.Title}}{{else}}(无标题){{end}}</div>
+			<div class="url"><a href="{{.URL}}" target="_blank" rel="noopener">{{.URL}}</a></div>
+			{{if and .FinalURL (ne .FinalURL .URL)}}<div class="url">跳转到: {{.FinalURL}}</div>{{end}}
+			<div class="meta">状态: {{.Status}}{{if .HTTPStatus}} · HTTP {{.HTTPStatus}}{{end}}{{if .Proxy}} · 代理 {{.Proxy}}{{end}}{{if .CapturedAt}} · {{.CapturedAt}}{{end}}</div>
+			{{with .Certificate}}<div class="meta">证书: {{.Subject}} · 颁发者 {{.Issuer}} · 有效期 {{.NotBefore.Format "2006-01-02"}} 至 {{.NotAfter.Format "2006-01-02"}}{{if .SANs}} · 域名 {{range $i, $san := .SANs}}{{if $i}}, {{end}}{{$san}}{{end}}{{end}}</div>
+			{{range .Errors}}<div class="error">证书问题: {{.}}</div>{{end}}{{end}}
+			{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+		</div>
+		{{end}}
+	</div>
+	<div class="overlay" id="overlay"><img id="overlayImage" src="" alt=""></div>
+	<script>
+		var overlay = document.getElementById('overlay');
+		var overlayImage = document.getElementById('overlayImage');
+
+		// 点击缩略图查看大图
+		document.querySelectorAll('.card img').forEach(function(img) {
+			img.addEventListener('click', function() {
+				overlayImage.src = img.src;
+				overlay.style.display = 'block';
+			});
+		});
+		overlay.addEventListener('click', function() {
+			overlay.style.display = 'none';
+		});
+
+		// 按成功/失败筛选
+		document.querySelectorAll('.filters button').forEach(function(button) {
+			button.addEventListener('click', function() {
+				var filter = button.getAttribute('data-filter');
+				document.querySelectorAll('.filters button').forEach(function(b) {
+					b.classList.toggle('active', b === button);
+				});
+				document.querySelectorAll('.card').forEach(function(card) {
+					var status = card.getAttribute('data-status');
+					var show = filter === 'all' || status === filter || (filter === 'failed' && status === 'cancelled');
+					card.style.display = show ? '' : 'none';
+				});
+			});
+		});
+	</script>
+</body>
+</html>
+`))
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 会话和浏览器配置的名称只允许字母、数字和少量符号，避免路径穿越
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// validName 检查会话或浏览器配置的名称
+func validName(name string) bool {
+	return namePattern.MatchString(name) && strings.Trim(name, ".") != ""
+}
+
+// Session 命名的Cookie集合，保存在配置的会话目录中，可在多次截图和批量任务中复用
+type Session struct {
+	Name      string    `json:"name"`
+	Cookies   []Cookie  `json:"cookies"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// sessionSummary 会话列表中的条目，不包含Cookie的值
+type sessionSummary struct {
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	Domains   []string  `json:"domains"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// sessionMu 串行化会话文件的读写
+var sessionMu sync.Mutex
+
+var errSessionNotFound = errors.New("会话不存在")
+
+// sessionPath 返回会话文件路径
+func sessionPath(name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("无效的会话名称 %q", name)
+	}
+	return filepath.Join(appConfig.SessionDir, name+".json"), nil
+}
+
+// loadSession 读取命名会话
+func loadSession(name string) (*Session, error) {
+	path, err := sessionPath(name)
+	if err != nil {
+		return nil, err
+	}
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", errSessionNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取会话失败: %v", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析会话 %s 失败: %v", name, err)
+	}
+	return &s, nil
+}
+
+// saveSession 保存命名会话，Cookie属于敏感信息，文件只对当前用户可读
+func saveSession(s *Session) error {
+	path, err := sessionPath(s.Name)
+	if err != nil {
+		return err
+	}
+	if err := validateCookies(s.Cookies); err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if err := os.MkdirAll(appConfig.SessionDir, 0700); err != nil {
+		return fmt.Errorf("无法创建会话目录: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("保存会话失败: %v", err)
+	}
+	return nil
+}
+
+// deleteSession 删除命名会话
+func deleteSession(name string) error {
+	path, err := sessionPath(name)
+	if err != nil {
+		return err
+	}
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", errSessionNotFound, name)
+	} else if err != nil {
+		return fmt.Errorf("删除会话失败: %v", err)
+	}
+	return nil
+}
+
+// listSessions 列出所有会话，按名称排序
+func listSessions() ([]sessionSummary, error) {
+	matches, err := filepath.Glob(filepath.Join(appConfig.SessionDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]sessionSummary, 0, len(matches))
+	for _, m := range matches {
+		s, err := loadSession(strings.TrimSuffix(filepath.Base(m), ".json"))
+		if err != nil {
+			continue
+		}
+		domains := make(map[string]bool)
+		for _, c := range s.Cookies {
+			if c.Domain != "" {
+				domains[strings.TrimPrefix(c.Domain, ".")] = true
+			}
+		}
+		summary := sessionSummary{Name: s.Name, Count: len(s.Cookies), Domains: []string{}, UpdatedAt: s.UpdatedAt}
+		for d := range domains {
+			summary.Domains = append(summary.Domains, d)
+		}
+		sort.Strings(summary.Domains)
+		list = append(list, summary)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// registerSessionHandlers 注册会话管理的HTTP接口
+func registerSessionHandlers() {
+	// 列出会话，不返回Cookie的值
+	http.HandleFunc("GET /api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		list, err := listSessions()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("读取会话列表失败: %v", err))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": list})
+	})
+
+	// 创建或替换会话，请求体为{"cookies": [...]}或{"cookiesTxt": "..."}
+	http.HandleFunc("PUT /api/sessions/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Cookies    []Cookie `json:"cookies"`
+			CookiesTxt string   `json:"cookiesTxt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		cookies := req.Cookies
+		if req.CookiesTxt != "" {
+			parsed, err := parseCookies(req.CookiesTxt)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			cookies = append(cookies, parsed...)
+		}
+		if len(cookies) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "Cookie列表为空")
+			return
+		}
+		s := &Session{Name: r.PathValue("name"), Cookies: cookies}
+		if err := saveSession(s); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": s.Name, "count": len(s.Cookies)})
+	})
+
+	// 删除会话
+	http.HandleFunc("DELETE /api/sessions/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := deleteSession(r.PathValue("name")); errors.Is(err, errSessionNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+}
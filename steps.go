@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// 单次截图最多执行的交互步骤数
+const maxSteps = 50
+
+// 单个步骤的默认超时时间(毫秒)，避免找不到元素时一直等到整个截图超时
+const defaultStepTimeout = 10000
+
+// 支持的交互动作
+const (
+	stepClick = "click" // 点击元素
+	stepType  = "type"  // 在元素中输入文字
+	stepHover = "hover" // 鼠标移到元素上
+	stepPress = "press" // 按键，如Enter、Escape
+	stepWait  = "wait"  // 等待毫秒数或元素可见
+	stepEval  = "eval"  // 执行JavaScript，返回Promise时等待其完成
+)
+
+// stepKeys press动作支持的特殊按键名称，其他单个字符按原样输入
+var stepKeys = map[string]string{
+	"enter":      kb.Enter,
+	"tab":        kb.Tab,
+	"escape":     kb.Escape,
+	"esc":        kb.Escape,
+	"backspace":  kb.Backspace,
+	"delete":     kb.Delete,
+	"space":      " ",
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"home":       kb.Home,
+	"end":        kb.End,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+}
+
+// Step 截图前执行的一个交互步骤
+type Step struct {
+	Action     string `json:"action"`
+	Selector   string `json:"selector,omitempty"`   // click、type、hover的目标元素，wait等待的元素
+	Text       string `json:"text,omitempty"`       // type输入的文字
+	Key        string `json:"key,omitempty"`        // press的按键
+	Delay      int    `json:"delay,omitempty"`      // wait固定等待的毫秒数
+	Expression string `json:"expression,omitempty"` // eval执行的JavaScript
+	Timeout    int    `json:"timeout,omitempty"`    // 本步骤的超时时间(毫秒)，默认10000
+}
+
+// UnmarshalJSON 除对象外还接受字符串简写，见parseStep
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := parseStep(text)
+		if err != nil {
+			return err
+		}
+		*s = parsed
+		return nil
+	}
+	type plain Step
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// parseStep 解析步骤简写，动作与参数以空格分隔：
+// "click #menu"、"type #q 关键字"、"hover .item"、"press Enter"、"wait 500"、"wait #result"、"eval 表达式"
+func parseStep(text string) (Step, error) {
+	action, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	arg = strings.TrimSpace(arg)
+	s := Step{Action: strings.ToLower(action)}
+	switch s.Action {
+	case stepClick, stepHover:
+		s.Selector = arg
+	case stepType:
+		// 选择器中不能含空格，其后的全部内容都是要输入的文字
+		s.Selector, s.Text, _ = strings.Cut(arg, " ")
+	case stepPress:
+		s.Key = arg
+	case stepWait:
+		if ms, err := strconv.Atoi(arg); err == nil {
+			s.Delay = ms
+		} else {
+			s.Selector = arg
+		}
+	case stepEval:
+		s.Expression = arg
+	default:
+		return s, fmt.Errorf("未知的交互动作 %q", action)
+	}
+	return s, s.validate()
+}
+
+// String 返回步骤的简写形式，用于错误信息
+func (s Step) String() string {
+	switch s.Action {
+	case stepType:
+		return s.Action + " " + s.Selector + " " + s.Text
+	case stepPress:
+		return s.Action + " " + s.Key
+	case stepWait:
+		if s.Selector == "" {
+			return s.Action + " " + strconv.Itoa(s.Delay)
+		}
+	case stepEval:
+		return s.Action + " " + s.Expression
+	}
+	return s.Action + " " + s.Selector
+}
+
+// validate 检查动作所需的参数
+func (s Step) validate() error {
+	switch s.Action {
+	case stepClick, stepType, stepHover:
+		if s.Selector == "" {
+			return fmt.Errorf("%s 缺少元素选择器", s.Action)
+		}
+	case stepPress:
+		if s.Key == "" {
+			return errors.New("press 缺少按键")
+		}
+		if _, ok := stepKeys[strings.ToLower(s.Key)]; !ok && len([]rune(s.Key)) != 1 {
+			return fmt.Errorf("不支持的按键 %q", s.Key)
+		}
+	case stepWait:
+		if s.Selector == "" && s.Delay <= 0 {
+			return errors.New("wait 缺少等待的毫秒数或元素选择器")
+		}
+	case stepEval:
+		if s.Expression == "" {
+			return errors.New("eval 缺少JavaScript表达式")
+		}
+	default:
+		return fmt.Errorf("未知的交互动作 %q", s.Action)
+	}
+	if s.Delay < 0 || s.Timeout < 0 {
+		return fmt.Errorf("%s 的等待时间不能为负数", s.Action)
+	}
+	return nil
+}
+
+// validateSteps 检查步骤列表
+func validateSteps(steps []Step) error {
+	if len(steps) > maxSteps {
+		return fmt.Errorf("交互步骤最多 %d 个", maxSteps)
+	}
+	for i, s := range steps {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("第%d步: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// StepError 交互步骤执行失败，记录是第几步(从1开始)以便在结果中单独展示
+type StepError struct {
+	Index int
+	Step  Step
+	Err   error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("第%d步(%s)失败: %v", e.Index, e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// hoverScript 返回元素中心点在视口中的坐标
+const hoverScript = `(function(sel) {
+	var el = document.querySelector(sel);
+	if (!el) {
+		return null;
+	}
+	el.scrollIntoView({block: 'center', inline: 'center'});
+	var r = el.getBoundingClientRect();
+	return [r.left + r.width / 2, r.top + r.height / 2];
+})(%s)`
+
+// runSteps 依次执行交互步骤，任一步骤失败时返回*StepError
+func runSteps(ctx context.Context, steps []Step) error {
+	for i, s := range steps {
+		if err := runStep(ctx, s); err != nil {
+			return &StepError{Index: i + 1, Step: s, Err: err}
+		}
+	}
+	return nil
+}
+
+// runStep 在单独的超时时间内执行一个步骤
+func runStep(parent context.Context, s Step) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultStepTimeout
+	}
+	// 固定等待不受步骤超时限制
+	if s.Action == stepWait && s.Selector == "" {
+		timeout += s.Delay
+	}
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+
+	var err error
+	switch s.Action {
+	case stepClick:
+		err = chromedp.Run(ctx, chromedp.Click(s.Selector, chromedp.ByQuery))
+	case stepType:
+		err = chromedp.Run(ctx, chromedp.SendKeys(s.Selector, s.Text, chromedp.ByQuery))
+	case stepHover:
+		err = hover(ctx, s.Selector)
+	case stepPress:
+		key, ok := stepKeys[strings.ToLower(s.Key)]
+		if !ok {
+			key = s.Key
+		}
+		err = chromedp.Run(ctx, chromedp.KeyEvent(key))
+	case stepWait:
+		if s.Selector != "" {
+			err = chromedp.Run(ctx, chromedp.WaitVisible(s.Selector, chromedp.ByQuery))
+		} else {
+			err = chromedp.Run(ctx, chromedp.Sleep(time.Duration(s.Delay)*time.Millisecond))
+		}
+	case stepEval:
+		err = chromedp.Run(ctx, chromedp.Evaluate(s.Expression, nil,
+			func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) },
+		))
+	}
+	if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+		if s.Selector != "" {
+			return fmt.Errorf("%dms内未找到可见的元素 %s", timeout, s.Selector)
+		}
+		return fmt.Errorf("超过%dms未完成", timeout)
+	}
+	return err
+}
+
+// hover 把鼠标移到元素中心，触发:hover样式和mouseover事件
+func hover(ctx context.Context, sel string) error {
+	if err := chromedp.Run(ctx, chromedp.WaitVisible(sel, chromedp.ByQuery)); err != nil {
+		return err
+	}
+	quoted, _ := json.Marshal(sel)
+	var point []float64
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(hoverScript, quoted), &point)); err != nil {
+		return err
+	}
+	if len(point) != 2 {
+		return fmt.Errorf("没有匹配 %s 的元素", sel)
+	}
+	return chromedp.Run(ctx, chromedp.MouseEvent(input.MouseMoved, point[0], point[1]))
+}
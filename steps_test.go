@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Step
+		wantErr bool
+	}{
+		{in: "click #menu", want: Step{Action: stepClick, Selector: "#menu"}},
+		{in: "  CLICK   #menu  ", want: Step{Action: stepClick, Selector: "#menu"}},
+		{in: "hover .item", want: Step{Action: stepHover, Selector: ".item"}},
+		{in: "type #q hello world", want: Step{Action: stepType, Selector: "#q", Text: "hello world"}},
+		{in: "type #q", want: Step{Action: stepType, Selector: "#q"}},
+		{in: "press Enter", want: Step{Action: stepPress, Key: "Enter"}},
+		{in: "press a", want: Step{Action: stepPress, Key: "a"}},
+		{in: "wait 500", want: Step{Action: stepWait, Delay: 500}},
+		{in: "wait #result", want: Step{Action: stepWait, Selector: "#result"}},
+		{in: "eval window.scrollTo(0, 100)", want: Step{Action: stepEval, Expression: "window.scrollTo(0, 100)"}},
+		{in: "click", wantErr: true},
+		{in: "press", wantErr: true},
+		{in: "press F13", wantErr: true},
+		{in: "wait", wantErr: true},
+		{in: "wait -5", wantErr: true},
+		{in: "eval", wantErr: true},
+		{in: "scroll 100", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseStep(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStep(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseStep(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 默认文件名模板
+const defaultFileNameTemplate = "{host}_{port}_{path}_{timestamp}"
+
+// 单个文件名片段的最大长度
+const maxFileNamePart = 120
+
+var (
+	unsafeFileChars     = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	fileNamePlaceholder = regexp.MustCompile(`\{[a-z]+\}`)
+	// 值为空的占位符连同其后的一个分隔符一起去掉，避免文件名中出现连续的"_"
+	emptyFieldRun = regexp.MustCompile("(\x00[_.-]?)+")
+)
+
+// emptyField 渲染时暂时代替值为空的占位符
+const emptyField = "\x00"
+
+// SaveInfo 生成文件名所需的截图信息
+type SaveInfo struct {
+	URL      string
+	Index    int    // 在批量任务中的序号，从0开始
+	JobID    string // 批量任务ID，单个截图为空
+	Viewport string // 多视口对比截图时的视口描述，如iphone-se_375x667@2x
+	Part     int    // 一次截图生成多张图片(多个元素或分段长图)时的序号，从1开始，0表示只有一张
+	Time     time.Time
+}
+
+// fileNameFields 计算模板中各占位符的值：
+// {host} {port} {path} {query} {scheme} {timestamp} {date} {time} {unix} {index} {job} {viewport} {part}
+func fileNameFields(info SaveInfo) map[string]string {
+	fields := map[string]string{
+		"timestamp": info.Time.Format("20060102_150405"),
+		"date":      info.Time.Format("20060102"),
+		"time":      info.Time.Format("150405"),
+		"unix":      fmt.Sprint(info.Time.Unix()),
+		"index":     fmt.Sprintf("%04d", info.Index+1),
+		"job":       info.JobID,
+		"viewport":  info.Viewport,
+		"part":      "",
+		"host":      info.URL, // 无法解析的URL整体作为{host}，其余URL相关的占位符留空
+		"port":      "",
+		"path":      "",
+		"query":     "",
+		"scheme":    "",
+	}
+	if info.JobID == "" {
+		fields["job"] = "single"
+	}
+	if info.Part > 0 {
+		fields["part"] = fmt.Sprint(info.Part)
+	}
+
+	u, err := url.Parse(info.URL)
+	if err != nil || u.Host == "" {
+		return fields
+	}
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		path = "root"
+	}
+	fields["host"] = u.Hostname()
+	fields["port"] = port
+	fields["path"] = path
+	fields["query"] = u.RawQuery
+	fields["scheme"] = u.Scheme
+	return fields
+}
+
+// sanitizeFilePart 将任意字符串转换为安全的文件名片段
+func sanitizeFilePart(s string) string {
+	s = strings.Trim(unsafeFileChars.ReplaceAllString(s, "_"), "_")
+	if len(s) > maxFileNamePart {
+		s = s[:maxFileNamePart]
+	}
+	if s == "." || s == ".." {
+		s = "_"
+	}
+	return s
+}
+
+// renderFileName 按模板生成相对路径(不含扩展名)，模板中的"/"可用于创建子目录
+func renderFileName(tmpl string, info SaveInfo) string {
+	// 多视口截图的模板中没有{viewport}时追加到末尾，否则同一URL的各个视口只能靠序号区分
+	if info.Viewport != "" && !strings.Contains(tmpl, "{viewport}") {
+		tmpl += "_{viewport}"
+	}
+	if info.Part > 0 && !strings.Contains(tmpl, "{part}") {
+		tmpl += "_{part}"
+	}
+	fields := fileNameFields(info)
+	var parts []string
+	for _, segment := range strings.Split(filepath.ToSlash(tmpl), "/") {
+		// 先替换占位符，占位符的值在替换时单独清理，避免引入路径分隔符
+		rendered := fileNamePlaceholder.ReplaceAllStringFunc(segment, func(token string) string {
+			if v, ok := fields[strings.Trim(token, "{}")]; ok {
+				if v = sanitizeFilePart(v); v == "" {
+					return emptyField
+				}
+				return v
+			}
+			return token
+		})
+		rendered = emptyFieldRun.ReplaceAllString(rendered, "")
+		// 去掉模板中写死的图片扩展名，扩展名由图片内容决定
+		for _, ext := range []string{".png", ".jpg", ".jpeg", ".webp", ".pdf"} {
+			rendered = strings.TrimSuffix(rendered, ext)
+		}
+		if part := sanitizeFilePart(rendered); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "screenshot"
+	}
+	return filepath.Join(parts...)
+}
+
+// imageExt 根据图片或PDF的内容判断扩展名
+func imageExt(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case mimePDF:
+		return ".pdf"
+	default:
+		return ".png"
+	}
+}
+
+// saveScreenshot 将截图写入保存目录，文件已存在时追加序号，返回文件路径
+func saveScreenshot(dir string, data []byte, info SaveInfo) (string, error) {
+	if info.Time.IsZero() {
+		info.Time = time.Now()
+	}
+	name := renderFileName(appConfig.FileNameTemplate, info)
+	ext := imageExt(data)
+
+	for i := 0; i < 1000; i++ {
+		candidate := name + ext
+		if i > 0 {
+			candidate = fmt.Sprintf("%s_%d%s", name, i, ext)
+		}
+		path := filepath.Join(dir, candidate)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("无法创建保存目录: %v", err)
+		}
+		// O_EXCL保证并发保存同名文件时不会互相覆盖
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("保存截图失败: %v", err)
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("保存截图失败: %v", err)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("保存截图失败: 同名文件过多 (%s)", name)
+}
+
+// saveResult 保存截图结果，有多张图片时逐个保存，返回各文件路径
+func saveResult(dir string, res *CaptureResult, info SaveInfo) ([]string, error) {
+	if len(res.Images) <= 1 {
+		file, err := saveScreenshot(dir, res.Image, info)
+		if err != nil {
+			return nil, err
+		}
+		return []string{file}, nil
+	}
+	files := make([]string, 0, len(res.Images))
+	for i, data := range res.Images {
+		info.Part = i + 1
+		file, err := saveScreenshot(dir, data, info)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// autoSaveResult 按配置自动保存截图结果，未开启自动保存时返回nil
+func autoSaveResult(res *CaptureResult, info SaveInfo) ([]string, error) {
+	if !appConfig.AutoSave {
+		return nil, nil
+	}
+	return saveResult(appConfig.OutputDir, res, info)
+}
+
+// openFolder 在系统文件管理器中打开目录
+func openFolder(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", dir)
+	case "darwin":
+		cmd = exec.Command("open", dir)
+	default:
+		cmd = exec.Command("xdg-open", dir)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
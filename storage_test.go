@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderFileName(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	tests := []struct {
+		name string
+		tmpl string
+		info SaveInfo
+		want string
+	}{
+		{
+			name: "default template",
+			tmpl: defaultFileNameTemplate,
+			info: SaveInfo{URL: "https://example.com/docs/intro?a=1", Time: at},
+			want: "example.com_443_docs_intro_20240506_070809",
+		},
+		{
+			name: "http root with port",
+			tmpl: defaultFileNameTemplate,
+			info: SaveInfo{URL: "http://localhost:8080/", Time: at},
+			want: "localhost_8080_root_20240506_070809",
+		},
+		{
+			name: "subdirectories and batch fields",
+			tmpl: "{job}/{date}/{index}_{host}",
+			info: SaveInfo{URL: "https://example.com", JobID: "job1", Index: 2, Time: at},
+			want: filepath.Join("job1", "20240506", "0003_example.com"),
+		},
+		{
+			name: "single capture job",
+			tmpl: "{job}_{host}",
+			info: SaveInfo{URL: "https://example.com", Time: at},
+			want: "single_example.com",
+		},
+		{
+			name: "viewport and part appended",
+			tmpl: "{host}",
+			info: SaveInfo{URL: "https://example.com", Viewport: "iphone-se_375x667@2x", Part: 2, Time: at},
+			want: "example.com_iphone-se_375x667_2x_2",
+		},
+		{
+			name: "empty fields drop their separator",
+			tmpl: "{host}_{query}_{part}_{time}",
+			info: SaveInfo{URL: "https://example.com/", Time: at},
+			want: "example.com_070809",
+		},
+		{
+			name: "unparseable url",
+			tmpl: defaultFileNameTemplate,
+			info: SaveInfo{URL: "not a url", Time: at},
+			want: "not_a_url_20240506_070809",
+		},
+		{
+			name: "url without host",
+			tmpl: "{scheme}_{host}_{port}_{path}",
+			info: SaveInfo{URL: "about:blank", Time: at},
+			want: "about_blank",
+		},
+		{
+			name: "placeholder values cannot escape the directory",
+			tmpl: "{path}",
+			info: SaveInfo{URL: "https://example.com/../../etc/passwd", Time: at},
+			want: ".._.._etc_passwd",
+		},
+		{
+			name: "unknown placeholder kept and extension trimmed",
+			tmpl: "{host}_{foo}.png",
+			info: SaveInfo{URL: "https://example.com", Time: at},
+			want: "example.com__foo",
+		},
+		{
+			name: "empty result",
+			tmpl: "{query}",
+			info: SaveInfo{URL: "https://example.com", Time: at},
+			want: "screenshot",
+		},
+	}
+	for _, tt := range tests {
+		if got := renderFileName(tt.tmpl, tt.info); got != tt.want {
+			t.Errorf("%s: renderFileName(%q) = %q, want %q", tt.name, tt.tmpl, got, tt.want)
+		}
+	}
+}
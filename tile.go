@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// 分块截图最多截取的页面高度(CSS像素)
+const maxTiledHeight = 100000
+
+// 单张拼接图片的最大高度(设备像素)，使拼接结果不超过JPEG的尺寸上限(65535)；
+// 请求WebP时拼接结果以PNG输出(见encodeTile)，同样适用此限制
+const maxStitchedHeight = 65000
+
+// TileOptions 分块截图参数：逐屏滚动截取视口，再在本地拼接成整页图片，
+// 用于超出Chrome纹理尺寸限制、直接整页截图会空白或截断的长页面
+type TileOptions struct {
+	MaxHeight   int `json:"maxHeight,omitempty"`   // 最多截取的页面高度(CSS像素)，0表示截取到页面底部
+	SplitHeight int `json:"splitHeight,omitempty"` // 按该高度(CSS像素)拆分为多张图片，0表示拼接为一张；超出单张图片的上限时自动拆分
+}
+
+// validate 检查分块参数
+func (t *TileOptions) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.MaxHeight < 0 || t.MaxHeight > maxTiledHeight {
+		return fmt.Errorf("最大截取高度 %d 超出范围(0-%d)", t.MaxHeight, maxTiledHeight)
+	}
+	if t.SplitHeight < 0 {
+		return fmt.Errorf("拆分高度 %d 不能为负数", t.SplitHeight)
+	}
+	return nil
+}
+
+// pageMetrics 页面尺寸(CSS像素)和设备像素比
+type pageMetrics struct {
+	Width          float64 `json:"width"`
+	Height         float64 `json:"height"`
+	ViewportHeight float64 `json:"viewportHeight"`
+	Ratio          float64 `json:"ratio"`
+}
+
+const pageMetricsScript = `({
+	width: document.documentElement.clientWidth,
+	height: Math.max(document.documentElement.scrollHeight, document.body ? document.body.scrollHeight : 0),
+	viewportHeight: window.innerHeight,
+	ratio: window.devicePixelRatio
+})`
+
+// 隐藏固定定位和粘性定位的元素，避免导航栏等在每一块中重复出现；恢复时还原原有的visibility
+const (
+	hideFixedScript = `(function() {
+	var hidden = [];
+	document.querySelectorAll('body *').forEach(function(e) {
+		var position = getComputedStyle(e).position;
+		if (position === 'fixed' || position === 'sticky') {
+			hidden.push([e, e.style.visibility]);
+			e.style.visibility = 'hidden';
+		}
+	});
+	window.__webcutHidden = hidden;
+})()`
+	restoreFixedScript = `(function() {
+	(window.__webcutHidden || []).forEach(function(h) {
+		h[0].style.visibility = h[1];
+	});
+	delete window.__webcutHidden;
+})()`
+)
+
+// captureTiled 逐屏滚动截图并拼接，返回一张或按SplitHeight拆分的多张图片及其MIME类型
+func captureTiled(ctx context.Context, opts CaptureOptions) ([][]byte, string, error) {
+	var m pageMetrics
+	if err := chromedp.Run(ctx, chromedp.Evaluate(pageMetricsScript, &m)); err != nil {
+		return nil, "", fmt.Errorf("获取页面尺寸失败: %v", err)
+	}
+	if m.Width <= 0 || m.Height <= 0 || m.ViewportHeight <= 0 {
+		return nil, "", errors.New("页面尺寸无效")
+	}
+	if m.Ratio <= 0 {
+		m.Ratio = 1
+	}
+
+	total := math.Min(m.Height, maxTiledHeight)
+	if t := opts.Tiled; t.MaxHeight > 0 {
+		total = math.Min(total, float64(t.MaxHeight))
+	}
+	partHeight := total
+	if t := opts.Tiled; t.SplitHeight > 0 {
+		partHeight = math.Min(total, float64(t.SplitHeight))
+	}
+	px := func(v float64) int { return int(math.Round(v * m.Ratio)) }
+	if px(partHeight) > maxStitchedHeight {
+		// 超出单张图片的上限时自动拆分为多张，保存时按分段序号命名
+		limit := math.Floor(maxStitchedHeight / m.Ratio)
+		fmt.Printf("拼接高度 %.0f 超出单张图片的上限，按 %.0f 拆分为多张图片\n", partHeight, limit)
+		partHeight = limit
+	}
+
+	// 各分段的画布，按需创建，整段拼接完成后立即编码以减少内存占用
+	count := int(math.Ceil(total / partHeight))
+	parts := make([]*image.RGBA, count)
+	images := make([][]byte, 0, count)
+	var mime string
+	width := px(m.Width)
+	flush := func(i int) error {
+		data, t, err := encodeTile(parts[i], opts)
+		if err != nil {
+			return err
+		}
+		images = append(images, data)
+		mime = t
+		parts[i] = nil
+		return nil
+	}
+
+	defer chromedp.Run(ctx, chromedp.Evaluate(restoreFixedScript, nil))
+	next := 0 // 下一个尚未编码的分段
+	for n := 0; float64(n)*m.ViewportHeight < total; n++ {
+		y := float64(n) * m.ViewportHeight
+		_, scrollY, err := scrollPage(ctx, &ScrollOptions{Y: y})
+		if err != nil {
+			return nil, "", err
+		}
+		if n == 1 {
+			// 第一屏之后隐藏固定元素
+			if err := chromedp.Run(ctx, chromedp.Evaluate(hideFixedScript, nil)); err != nil {
+				return nil, "", fmt.Errorf("隐藏固定元素失败: %v", err)
+			}
+		}
+		data, err := page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatPng).WithFromSurface(true).Do(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("截取第%d块失败: %v", n+1, err)
+		}
+		tile, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("解码截图失败: %v", err)
+		}
+
+		// 把这一块画到与之重叠的各个分段上
+		top := px(scrollY)
+		bottom := top + tile.Bounds().Dy()
+		for i := next; i < count; i++ {
+			partTop := px(float64(i) * partHeight)
+			partBottom := px(math.Min(float64(i+1)*partHeight, total))
+			if partTop >= bottom {
+				break
+			}
+			if parts[i] == nil {
+				parts[i] = image.NewRGBA(image.Rect(0, 0, width, partBottom-partTop))
+			}
+			draw.Draw(parts[i], image.Rect(0, top-partTop, width, bottom-partTop), tile, tile.Bounds().Min, draw.Src)
+			if partBottom <= bottom {
+				if err := flush(i); err != nil {
+					return nil, "", err
+				}
+				next = i + 1
+			}
+		}
+
+		// 页面无法继续滚动时说明已经到达底部
+		if scrollY < y {
+			break
+		}
+	}
+	// 页面实际高度小于预期时，编码剩余的分段
+	for i := next; i < count; i++ {
+		if parts[i] != nil {
+			if err := flush(i); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if len(images) == 0 {
+		return nil, "", errors.New("没有截取到任何内容")
+	}
+	return images, mime, nil
+}
+
+// encodeTile 按请求的格式编码拼接后的图片，WebP没有标准库编码器，改用PNG
+func encodeTile(img image.Image, opts CaptureOptions) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if f := opts.imageFormat(); f.format == page.CaptureScreenshotFormatJpeg {
+		quality := opts.Quality
+		if quality <= 0 {
+			quality = defaultImageQuality
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("编码图片失败: %v", err)
+		}
+		return buf.Bytes(), f.mime, nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", fmt.Errorf("编码图片失败: %v", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// 证书检查模式
+const (
+	tlsLenient = "lenient" // 证书有错误时仍然截图，在结果中记录错误(默认)
+	tlsStrict  = "strict"  // 证书有错误时截图失败
+)
+
+// 单独获取证书时的超时时间
+const certProbeTimeout = 15 * time.Second
+
+// validateTLSMode 检查证书检查模式
+func validateTLSMode(mode string) error {
+	switch mode {
+	case "", tlsLenient, tlsStrict:
+		return nil
+	}
+	return fmt.Errorf("不支持的证书检查模式 %q，可用: strict, lenient", mode)
+}
+
+// tlsMode 返回截图使用的证书检查模式，请求中未设置时使用配置文件中的值
+func (o CaptureOptions) tlsMode() string {
+	if o.TLS != "" {
+		return o.TLS
+	}
+	if appConfig.TLS != "" {
+		return appConfig.TLS
+	}
+	return tlsLenient
+}
+
+// certAuthorities 额外信任的CA证书，如企业内部PKI的根证书和中间证书
+type certAuthorities struct {
+	roots *x509.CertPool // 系统根证书加上额外的CA，用于Go发出的请求和证书校验
+	spki  []string       // 各CA公钥的SHA-256哈希(Base64)，作为Chrome的ignore-certificate-errors-spki-list
+}
+
+// appCAs 配置文件或命令行中指定的CA证书，为nil时只信任系统根证书
+var appCAs *certAuthorities
+
+// loadCABundle 读取PEM格式的CA证书文件，可以包含多个证书；path为空时返回nil
+func loadCABundle(path string) (*certAuthorities, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书文件失败: %v", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	cas := &certAuthorities{roots: roots}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析CA证书文件 %s 失败: %v", path, err)
+		}
+		cas.roots.AddCert(cert)
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		cas.spki = append(cas.spki, base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	if len(cas.spki) == 0 {
+		return nil, fmt.Errorf("CA证书文件 %s 中没有PEM格式的证书", path)
+	}
+	return cas, nil
+}
+
+// rootCAs 返回校验服务器证书使用的根证书，未指定CA证书文件时返回nil，即使用系统根证书
+func rootCAs() *x509.CertPool {
+	if appCAs == nil {
+		return nil
+	}
+	return appCAs.roots
+}
+
+// verifyChain 校验证书链和主机名，roots为nil时使用系统根证书
+func verifyChain(chain []*x509.Certificate, host string, roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: host})
+	return err
+}
+
+// applyTLSMode 按模式设置标签页是否忽略证书错误，只影响当前标签页
+func applyTLSMode(mode string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := security.SetIgnoreCertificateErrors(mode != tlsStrict).Do(ctx); err != nil {
+			return fmt.Errorf("设置证书检查模式失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// CertificateInfo 主文档使用的服务器证书
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans,omitempty"` // 证书中的DNS名称和IP地址
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	Errors    []string  `json:"errors,omitempty"` // 证书校验错误，为空表示证书有效
+}
+
+// CertificateError 严格模式下证书校验失败，附带能获取到的证书信息
+type CertificateError struct {
+	Certificate *CertificateInfo // 无法获取证书时为nil
+	Err         error
+}
+
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("证书校验失败: %v", e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// isCertificateError 判断导航错误是否由浏览器拒绝证书引起
+func isCertificateError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "net::ERR_CERT_")
+}
+
+// certFailures 页面中因证书错误加载失败的请求，每个源只记录一次
+type certFailures struct {
+	mu       sync.Mutex
+	urls     map[network.RequestID]string
+	seen     map[string]bool
+	failures []string
+}
+
+// trackCertFailures 开始记录证书错误导致的请求失败，需在导航前调用，监听随ctx结束而停止
+func trackCertFailures(ctx context.Context) *certFailures {
+	f := &certFailures{urls: make(map[network.RequestID]string), seen: make(map[string]bool)}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			f.urls[e.RequestID] = e.Request.URL
+		case *network.EventLoadingFinished:
+			delete(f.urls, e.RequestID)
+		case *network.EventLoadingFailed:
+			raw := f.urls[e.RequestID]
+			delete(f.urls, e.RequestID)
+			if !strings.HasPrefix(e.ErrorText, "net::ERR_CERT_") {
+				return
+			}
+			origin := raw
+			if u, err := url.Parse(raw); err == nil && u.Host != "" {
+				origin = u.Scheme + "://" + u.Host
+			}
+			if !f.seen[origin] {
+				f.seen[origin] = true
+				f.failures = append(f.failures, origin+": "+e.ErrorText)
+			}
+		}
+	})
+	return f
+}
+
+// list 返回已记录的证书错误
+func (f *certFailures) list() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.failures...)
+}
+
+// inspectCertificate 读取页面的服务器证书并校验，非HTTPS页面或无法获取证书时返回nil
+func inspectCertificate(ctx context.Context, pageURL string, proxy *proxyServer) *CertificateInfo {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	chain, err := browserCertificate(ctx, u.Scheme+"://"+u.Host)
+	if err != nil {
+		// 浏览器没有保留证书时(如导航失败、响应由Go代为请求)，单独连接一次获取
+		if chain, err = probeCertificate(ctx, pageURL, proxy); err != nil {
+			fmt.Printf("获取 %s 的证书失败: %v\n", u.Host, err)
+			return nil
+		}
+	}
+	return describeCertificate(chain, u.Hostname())
+}
+
+// browserCertificate 返回浏览器访问该源时收到的证书链
+func browserCertificate(ctx context.Context, origin string) ([]*x509.Certificate, error) {
+	var encoded []string
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		encoded, err = network.GetCertificate(origin).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for _, s := range encoded {
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("浏览器没有该源的证书")
+	}
+	return chain, nil
+}
+
+// probeCertificate 通过与浏览器相同的代理发出HEAD请求，读取服务器返回的证书链
+func probeCertificate(ctx context.Context, pageURL string, proxy *proxyServer) ([]*x509.Certificate, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		// 只读取证书，校验由describeCertificate完成
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	if proxy != nil {
+		transport.Proxy = proxy.httpProxy()
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   certProbeTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil, errors.New("服务器没有返回证书")
+	}
+	return resp.TLS.PeerCertificates, nil
+}
+
+// describeCertificate 提取证书链中服务器证书的信息，并分别检查有效期、主机名和颁发机构
+func describeCertificate(chain []*x509.Certificate, host string) *CertificateInfo {
+	leaf := chain[0]
+	info := &CertificateInfo{
+		Subject:   leaf.Subject.String(),
+		SANs:      append([]string{}, leaf.DNSNames...),
+		Issuer:    leaf.Issuer.String(),
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+	}
+	for _, ip := range leaf.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	now := time.Now()
+	checkTime := now
+	switch {
+	case now.Before(leaf.NotBefore):
+		info.Errors = append(info.Errors, fmt.Sprintf("证书尚未生效(生效时间 %s)", leaf.NotBefore.Local().Format("2006-01-02 15:04:05")))
+		checkTime = leaf.NotBefore
+	case now.After(leaf.NotAfter):
+		info.Errors = append(info.Errors, fmt.Sprintf("证书已过期(过期时间 %s)", leaf.NotAfter.Local().Format("2006-01-02 15:04:05")))
+		checkTime = leaf.NotAfter
+	}
+	if err := leaf.VerifyHostname(host); err != nil {
+		info.Errors = append(info.Errors, fmt.Sprintf("证书与主机名 %s 不匹配", host))
+	}
+
+	// 有效期已单独检查，这里在有效期内校验证书链，避免同一个问题重复报告
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{Roots: rootCAs(), Intermediates: intermediates, CurrentTime: checkTime})
+	var unknown x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case err == nil:
+	case errors.As(err, &unknown) && bytes.Equal(leaf.RawIssuer, leaf.RawSubject):
+		info.Errors = append(info.Errors, "自签名证书，不受信任")
+	case errors.As(err, &unknown):
+		info.Errors = append(info.Errors, fmt.Sprintf("证书由不受信任的颁发机构签发: %s", leaf.Issuer.String()))
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		info.Errors = append(info.Errors, fmt.Sprintf("证书链中的 %s 不在有效期内", invalid.Cert.Subject.String()))
+	default:
+		info.Errors = append(info.Errors, fmt.Sprintf("证书链校验失败: %v", err))
+	}
+	return info
+}
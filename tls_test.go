@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTLSMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: ""},
+		{mode: tlsLenient},
+		{mode: tlsStrict},
+		{mode: "Strict", wantErr: true},
+		{mode: "off", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateTLSMode(tt.mode); (err != nil) != tt.wantErr {
+			t.Errorf("validateTLSMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+		}
+	}
+}
+
+// testCert 测试用的证书及其私钥
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert 生成证书，parent为nil时生成自签名证书；tmpl中未设置的字段使用默认值
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = time.Now().Add(time.Hour)
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().AddDate(-1, 0, 0),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newTestLeaf(t *testing.T, ca *testCert, notBefore, notAfter time.Time) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "www.example.com"},
+		DNSNames:    []string{"www.example.com", "example.com"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+}
+
+func TestDescribeCertificate(t *testing.T) {
+	trusted := newTestCA(t, "Test Root CA")
+	untrusted := newTestCA(t, "Unknown CA")
+	roots := x509.NewCertPool()
+	roots.AddCert(trusted.cert)
+	prev := appCAs
+	appCAs = &certAuthorities{roots: roots}
+	t.Cleanup(func() { appCAs = prev })
+
+	now := time.Now()
+	valid := newTestLeaf(t, trusted, time.Time{}, time.Time{})
+	expired := newTestLeaf(t, trusted, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	future := newTestLeaf(t, trusted, now.Add(24*time.Hour), now.Add(48*time.Hour))
+	unknown := newTestLeaf(t, untrusted, time.Time{}, time.Time{})
+	selfSigned := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"www.example.com"}}, nil)
+
+	tests := []struct {
+		name   string
+		chain  []*x509.Certificate
+		host   string
+		errors []string // 每个错误应包含的文字，为空表示证书有效
+	}{
+		{name: "valid", chain: []*x509.Certificate{valid.cert}, host: "www.example.com"},
+		{name: "valid ip", chain: []*x509.Certificate{valid.cert}, host: "127.0.0.1"},
+		{name: "host mismatch", chain: []*x509.Certificate{valid.cert}, host: "other.test", errors: []string{"不匹配"}},
+		{name: "expired", chain: []*x509.Certificate{expired.cert}, host: "www.example.com", errors: []string{"已过期"}},
+		{name: "not yet valid", chain: []*x509.Certificate{future.cert}, host: "www.example.com", errors: []string{"尚未生效"}},
+		{name: "unknown authority", chain: []*x509.Certificate{unknown.cert, untrusted.cert}, host: "www.example.com", errors: []string{"不受信任的颁发机构"}},
+		{name: "self-signed", chain: []*x509.Certificate{selfSigned.cert}, host: "www.example.com", errors: []string{"自签名"}},
+		{name: "expired and mismatch", chain: []*x509.Certificate{expired.cert}, host: "other.test", errors: []string{"已过期", "不匹配"}},
+	}
+	for _, tt := range tests {
+		info := describeCertificate(tt.chain, tt.host)
+		if len(info.Errors) != len(tt.errors) {
+			t.Errorf("%s: errors = %q, want %d errors", tt.name, info.Errors, len(tt.errors))
+			continue
+		}
+		for i, want := range tt.errors {
+			if !strings.Contains(info.Errors[i], want) {
+				t.Errorf("%s: errors[%d] = %q, want it to contain %q", tt.name, i, info.Errors[i], want)
+			}
+		}
+	}
+
+	info := describeCertificate([]*x509.Certificate{valid.cert}, "www.example.com")
+	if info.Subject != "CN=www.example.com" || info.Issuer != "CN=Test Root CA" {
+		t.Errorf("subject/issuer = %q/%q", info.Subject, info.Issuer)
+	}
+	if want := []string{"www.example.com", "example.com", "127.0.0.1"}; !reflect.DeepEqual(info.SANs, want) {
+		t.Errorf("SANs = %q, want %q", info.SANs, want)
+	}
+	if !info.NotAfter.Equal(valid.cert.NotAfter) {
+		t.Errorf("NotAfter = %v, want %v", info.NotAfter, valid.cert.NotAfter)
+	}
+}
+
+func TestLoadCABundle(t *testing.T) {
+	root := newTestCA(t, "Bundle Root CA")
+	other := newTestCA(t, "Bundle Other CA")
+	certPEM := func(c *testCert) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantSPKI int // 0表示返回nil
+		wantErr  bool
+	}{
+		{name: "no path", path: ""},
+		{name: "single", path: write("single.pem", certPEM(root)), wantSPKI: 1},
+		{name: "bundle with other blocks", path: write("bundle.pem", "intermediate\n"+certPEM(root)+
+			string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}}))+certPEM(other)), wantSPKI: 2},
+		{name: "missing file", path: filepath.Join(dir, "missing.pem"), wantErr: true},
+		{name: "no certificates", path: write("empty.pem", "not a pem file"), wantErr: true},
+		{name: "broken certificate", path: write("broken.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("junk")}))), wantErr: true},
+	}
+	for _, tt := range tests {
+		cas, err := loadCABundle(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: loadCABundle() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if tt.wantSPKI == 0 {
+			if cas != nil {
+				t.Errorf("%s: loadCABundle() = %+v, want nil", tt.name, cas)
+			}
+			continue
+		}
+		if len(cas.spki) != tt.wantSPKI {
+			t.Errorf("%s: got %d SPKI hashes, want %d", tt.name, len(cas.spki), tt.wantSPKI)
+		}
+	}
+
+	// 加载的CA应能校验其签发的证书，SPKI哈希与Chrome的格式一致
+	cas, err := loadCABundle(write("root.pem", certPEM(root)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := newTestLeaf(t, root, time.Time{}, time.Time{})
+	if _, err := leaf.cert.Verify(x509.VerifyOptions{Roots: cas.roots, DNSName: "www.example.com"}); err != nil {
+		t.Errorf("leaf signed by the bundle CA failed to verify: %v", err)
+	}
+	sum := sha256.Sum256(root.cert.RawSubjectPublicKeyInfo)
+	if want := base64.StdEncoding.EncodeToString(sum[:]); cas.spki[0] != want {
+		t.Errorf("spki = %q, want %q", cas.spki[0], want)
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	root := newTestCA(t, "Verify Root CA")
+	leaf := newTestLeaf(t, root, time.Time{}, time.Time{})
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	tests := []struct {
+		name    string
+		host    string
+		roots   *x509.CertPool
+		wantErr bool
+	}{
+		{name: "bundle root", host: "www.example.com", roots: roots},
+		{name: "system roots", host: "www.example.com", roots: nil, wantErr: true},
+		{name: "host mismatch", host: "other.test", roots: roots, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := verifyChain([]*x509.Certificate{leaf.cert}, tt.host, tt.roots); (err != nil) != tt.wantErr {
+			t.Errorf("%s: verifyChain() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
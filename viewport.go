@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// 未指定视口时使用的桌面窗口大小
+const (
+	defaultViewportWidth  = 1280
+	defaultViewportHeight = 800
+)
+
+// 视口尺寸和像素比的上限，避免生成超大图片耗尽内存
+const (
+	maxViewportSize  = 10000
+	maxViewportScale = 4
+)
+
+// DevicePreset 内置的设备预设
+type DevicePreset struct {
+	Name      string  `json:"name"`
+	Label     string  `json:"label"`
+	Width     int64   `json:"width"`
+	Height    int64   `json:"height"`
+	Scale     float64 `json:"scale"`
+	Mobile    bool    `json:"mobile"`
+	UserAgent string  `json:"userAgent,omitempty"`
+}
+
+const (
+	iosUserAgent     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	ipadUserAgent    = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"
+)
+
+// devicePresets 按界面中的展示顺序排列
+var devicePresets = []DevicePreset{
+	{Name: "desktop", Label: "桌面", Width: 1280, Height: 800, Scale: 1},
+	{Name: "1080p", Label: "桌面 1080p", Width: 1920, Height: 1080, Scale: 1},
+	{Name: "4k", Label: "桌面 4K", Width: 3840, Height: 2160, Scale: 1},
+	{Name: "iphone-se", Label: "iPhone SE", Width: 375, Height: 667, Scale: 2, Mobile: true, UserAgent: iosUserAgent},
+	{Name: "iphone-15", Label: "iPhone 15", Width: 393, Height: 852, Scale: 3, Mobile: true, UserAgent: iosUserAgent},
+	{Name: "iphone-15-pro-max", Label: "iPhone 15 Pro Max", Width: 430, Height: 932, Scale: 3, Mobile: true, UserAgent: iosUserAgent},
+	{Name: "pixel-8", Label: "Pixel 8", Width: 412, Height: 915, Scale: 2.625, Mobile: true, UserAgent: androidUserAgent},
+	{Name: "ipad", Label: "iPad", Width: 820, Height: 1180, Scale: 2, Mobile: true, UserAgent: ipadUserAgent},
+	{Name: "ipad-pro", Label: "iPad Pro 12.9", Width: 1024, Height: 1366, Scale: 2, Mobile: true, UserAgent: ipadUserAgent},
+}
+
+// findDevicePreset 按名称查找设备预设，忽略大小写
+func findDevicePreset(name string) (DevicePreset, bool) {
+	for _, d := range devicePresets {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return DevicePreset{}, false
+}
+
+// devicePresetNames 返回所有预设名称，用于提示信息
+func devicePresetNames() string {
+	names := make([]string, 0, len(devicePresets))
+	for _, d := range devicePresets {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ViewportOptions 视口与设备模拟参数，指定Device时以预设为基础，其余字段覆盖预设中的值
+type ViewportOptions struct {
+	Device string  `json:"device,omitempty"`
+	Width  int64   `json:"width,omitempty"`
+	Height int64   `json:"height,omitempty"`
+	Scale  float64 `json:"scale,omitempty"`  // 设备像素比，2或3可生成高清截图
+	Mobile bool    `json:"mobile,omitempty"` // 模拟移动设备，同时开启触屏
+}
+
+// UnmarshalJSON 除对象外还接受字符串简写，见parseViewport
+func (v *ViewportOptions) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := parseViewport(s)
+		if err != nil {
+			return err
+		}
+		*v = parsed
+		return nil
+	}
+	type plain ViewportOptions
+	return json.Unmarshal(data, (*plain)(v))
+}
+
+// parseViewport 解析视口简写: "375"(宽度)、"1024x768"(宽×高)，可追加"@2"指定像素比；
+// 其他字符串视为设备预设名称
+func parseViewport(s string) (ViewportOptions, error) {
+	s = strings.TrimSpace(s)
+	size, scale, hasScale := strings.Cut(s, "@")
+	if size == "" || size[0] < '0' || size[0] > '9' {
+		if hasScale {
+			return ViewportOptions{}, fmt.Errorf("无效的视口 %q", s)
+		}
+		return ViewportOptions{Device: s}, nil
+	}
+
+	var v ViewportOptions
+	width, height, hasHeight := strings.Cut(strings.ReplaceAll(strings.ToLower(size), "×", "x"), "x")
+	var err error
+	if v.Width, err = strconv.ParseInt(width, 10, 64); err != nil {
+		return v, fmt.Errorf("无效的视口宽度 %q", s)
+	}
+	if hasHeight {
+		if v.Height, err = strconv.ParseInt(height, 10, 64); err != nil {
+			return v, fmt.Errorf("无效的视口高度 %q", s)
+		}
+	}
+	if hasScale {
+		if v.Scale, err = strconv.ParseFloat(scale, 64); err != nil {
+			return v, fmt.Errorf("无效的设备像素比 %q", s)
+		}
+	}
+	return v, nil
+}
+
+// resolve 合并预设与自定义参数，返回最终生效的视口设置
+func (v *ViewportOptions) resolve() (DevicePreset, error) {
+	vp := DevicePreset{Width: defaultViewportWidth, Height: defaultViewportHeight, Scale: 1}
+	if v == nil {
+		return vp, nil
+	}
+	if v.Device != "" {
+		preset, ok := findDevicePreset(v.Device)
+		if !ok {
+			return vp, fmt.Errorf("未知的设备预设 %q，可用: %s", v.Device, devicePresetNames())
+		}
+		vp = preset
+	}
+	if v.Width > 0 {
+		vp.Width = v.Width
+	}
+	if v.Height > 0 {
+		vp.Height = v.Height
+	}
+	if v.Scale > 0 {
+		vp.Scale = v.Scale
+	}
+	if v.Mobile {
+		vp.Mobile = true
+	}
+	if v.Width < 0 || v.Height < 0 || vp.Width > maxViewportSize || vp.Height > maxViewportSize {
+		return vp, fmt.Errorf("视口尺寸 %d×%d 超出范围(1-%d)", vp.Width, vp.Height, maxViewportSize)
+	}
+	if v.Scale < 0 || vp.Scale > maxViewportScale {
+		return vp, fmt.Errorf("设备像素比 %g 超出范围(0-%d]", vp.Scale, maxViewportScale)
+	}
+	return vp, nil
+}
+
+// label 视口的简短描述，用于界面展示和文件名
+func (vp DevicePreset) label() string {
+	size := fmt.Sprintf("%dx%d", vp.Width, vp.Height)
+	if vp.Scale != 1 {
+		size += fmt.Sprintf("@%gx", vp.Scale)
+	}
+	if vp.Name != "" {
+		return vp.Name + "_" + size
+	}
+	return size
+}
+
+// emulateViewport 在标签页上应用视口、像素比、移动设备和触屏模拟，需在导航前执行；
+// 桌面视口会显式关闭移动设备和触屏模拟，避免沿用同一标签页上一次的设置。
+// 预设中的UA由applyHeaders设置
+func emulateViewport(vp DevicePreset) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := emulation.SetDeviceMetricsOverride(vp.Width, vp.Height, vp.Scale, vp.Mobile).Do(ctx); err != nil {
+			return fmt.Errorf("设置视口失败: %v", err)
+		}
+		if err := emulation.SetTouchEmulationEnabled(vp.Mobile).Do(ctx); err != nil {
+			return fmt.Errorf("设置触屏模拟失败: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestParseViewport(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ViewportOptions
+		wantErr bool
+	}{
+		{in: "375", want: ViewportOptions{Width: 375}},
+		{in: "1024x768", want: ViewportOptions{Width: 1024, Height: 768}},
+		{in: "1024X768", want: ViewportOptions{Width: 1024, Height: 768}},
+		{in: "1024×768", want: ViewportOptions{Width: 1024, Height: 768}},
+		{in: " 390x844@3 ", want: ViewportOptions{Width: 390, Height: 844, Scale: 3}},
+		{in: "375@2.5", want: ViewportOptions{Width: 375, Scale: 2.5}},
+		{in: "iphone-15", want: ViewportOptions{Device: "iphone-15"}},
+		{in: "", want: ViewportOptions{}},
+		{in: "iphone-15@2", wantErr: true},
+		{in: "12ax768", wantErr: true},
+		{in: "1024xabc", wantErr: true},
+		{in: "1024x768@", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseViewport(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseViewport(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseViewport(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestViewportResolve(t *testing.T) {
+	iphone, _ := findDevicePreset("iphone-15")
+	tests := []struct {
+		name    string
+		in      *ViewportOptions
+		want    DevicePreset
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: DevicePreset{Width: defaultViewportWidth, Height: defaultViewportHeight, Scale: 1}},
+		{name: "width only", in: &ViewportOptions{Width: 375}, want: DevicePreset{Width: 375, Height: defaultViewportHeight, Scale: 1}},
+		{name: "preset", in: &ViewportOptions{Device: "IPHONE-15"}, want: iphone},
+		{name: "preset override", in: &ViewportOptions{Device: "iphone-15", Width: 400, Scale: 2}, want: func() DevicePreset {
+			vp := iphone
+			vp.Width, vp.Scale = 400, 2
+			return vp
+		}()},
+		{name: "mobile", in: &ViewportOptions{Width: 500, Mobile: true}, want: DevicePreset{Width: 500, Height: defaultViewportHeight, Scale: 1, Mobile: true}},
+		{name: "unknown preset", in: &ViewportOptions{Device: "nokia"}, wantErr: true},
+		{name: "too wide", in: &ViewportOptions{Width: maxViewportSize + 1}, wantErr: true},
+		{name: "negative height", in: &ViewportOptions{Height: -1}, wantErr: true},
+		{name: "scale too large", in: &ViewportOptions{Scale: maxViewportScale + 1}, wantErr: true},
+		{name: "negative scale", in: &ViewportOptions{Scale: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.resolve()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: resolve() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s: resolve() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}